@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nownabe/moneysaver/slack"
+	"github.com/slack-go/slack/slackevents"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	homeTopCategories = 3
+	homeRecent        = 5
+	progressBarWidth  = 10
+)
+
+// categoryTotal is the total of a category in a month.
+type categoryTotal struct {
+	category string
+	amount   int64
+}
+
+// channelSummary is a budget channel on the dashboard.
+type channelSummary struct {
+	ch     *channel
+	name   string
+	budget int64
+	total  int64
+	// Carried over from previous months
+	carry      int64
+	categories []categoryTotal
+	recent     []*expenditure
+}
+
+// summarize totals expenditures of the channel in a month.
+func summarize(ch *channel, name, month string, exs []*expenditure) *channelSummary {
+	s := &channelSummary{ch: ch, name: name, budget: ch.budgetFor(month)}
+
+	byCategory := map[string]int64{}
+
+	for _, ex := range exs {
+		s.total += ex.Amount
+		byCategory[ex.Category] += ex.Amount
+	}
+
+	for c, a := range byCategory {
+		s.categories = append(s.categories, categoryTotal{category: c, amount: a})
+	}
+
+	sort.Slice(s.categories, func(i, j int) bool {
+		if s.categories[i].amount != s.categories[j].amount {
+			return s.categories[i].amount > s.categories[j].amount
+		}
+
+		return s.categories[i].category < s.categories[j].category
+	})
+
+	if len(s.categories) > homeTopCategories {
+		s.categories = s.categories[:homeTopCategories]
+	}
+
+	recent := make([]*expenditure, len(exs))
+	copy(recent, exs)
+
+	sort.Slice(recent, func(i, j int) bool {
+		if ei, ej := recent[i].effective(), recent[j].effective(); !ei.Equal(ej) {
+			return ei.After(ej)
+		}
+
+		return recent[i].TS > recent[j].TS
+	})
+
+	if len(recent) > homeRecent {
+		recent = recent[:homeRecent]
+	}
+
+	s.recent = recent
+
+	return s
+}
+
+// progressBar renders the ratio of total to budget.
+func progressBar(total, budget int64) string {
+	if budget <= 0 {
+		return ""
+	}
+
+	percent := total * 100 / budget
+
+	filled := int(percent * progressBarWidth / 100)
+	if filled > progressBarWidth {
+		filled = progressBarWidth
+	} else if filled < 0 {
+		filled = 0
+	}
+
+	return strings.Repeat("▓", filled) + strings.Repeat("░", progressBarWidth-filled) + fmt.Sprintf(" %d%%", percent)
+}
+
+// homeView renders the dashboard.
+func homeView(l lang, summaries []*channelSummary) *slack.View {
+	blocks := []*slack.Block{
+		{Type: slack.BlockHeader, Text: slack.PlainText(l.t(msgHomeTitle))},
+	}
+
+	if len(summaries) == 0 {
+		blocks = append(blocks, &slack.Block{Type: slack.BlockSection, Text: slack.Markdown(l.t(msgHomeNoChannels))})
+	}
+
+	for _, s := range summaries {
+		cur := s.ch.currency()
+		limit := s.budget + s.carry
+
+		remaining := l.t(msgHomeRemaining, humanizeIn(limit-s.total, cur), humanizeIn(limit, cur))
+		if s.ch.rollover() != rolloverNone {
+			remaining += " (" + l.t(msgHomeRollover, humanizeIn(s.carry, cur)) + ")"
+		}
+
+		blocks = append(blocks,
+			&slack.Block{Type: slack.BlockDivider},
+			&slack.Block{
+				Type: slack.BlockSection,
+				Text: slack.Markdown(fmt.Sprintf("*<#%s>*\n%s\n`%s`", s.ch.ID, remaining, progressBar(s.total, limit))),
+			},
+		)
+
+		if len(s.recent) == 0 {
+			blocks = append(blocks, &slack.Block{
+				Type:     slack.BlockContext,
+				Elements: []interface{}{slack.Markdown(l.t(msgHomeEmpty))},
+			})
+
+			continue
+		}
+
+		categories := make([]string, 0, len(s.categories))
+		for _, c := range s.categories {
+			categories = append(categories, categoryLabel(l, c.category)+" "+humanizeIn(c.amount, cur))
+		}
+
+		recent := make([]string, 0, len(s.recent))
+		for _, ex := range s.recent {
+			line := ex.effective().Format("1/2") + " " + humanizeIn(ex.Amount, cur) + " " + categoryLabel(l, ex.Category)
+			if ex.Memo != "" {
+				line += " " + ex.Memo
+			}
+
+			recent = append(recent, line)
+		}
+
+		blocks = append(blocks, &slack.Block{
+			Type: slack.BlockSection,
+			Fields: []*slack.Text{
+				slack.Markdown("*" + l.t(msgHomeTopCategories) + "*\n" + strings.Join(categories, "\n")),
+				slack.Markdown("*" + l.t(msgHomeRecent) + "*\n" + strings.Join(recent, "\n")),
+			},
+		})
+	}
+
+	return &slack.View{Type: slack.ViewHome, Blocks: blocks}
+}
+
+func categoryLabel(l lang, c string) string {
+	if c == "" {
+		return l.t(msgUncategorized)
+	}
+
+	return c
+}
+
+// processAppHomeOpened publishes the dashboard of budget channels which the user is a member of.
+func (p *eventProcessor) processAppHomeOpened(ctx context.Context, ev *slackevents.AppHomeOpenedEvent) (err error) {
+	ctx, end := startSpan(ctx, "processAppHomeOpened", attribute.String("slack.user", ev.User))
+	defer end(&err)
+
+	if ev.Tab != "home" {
+		return nil
+	}
+
+	convs, err := p.slack.UsersConversations(ctx, ev.User)
+	if err != nil {
+		return fmt.Errorf("p.slack.UsersConversations: %w", err)
+	}
+
+	ids := make([]string, 0, len(convs))
+	for _, conv := range convs {
+		ids = append(ids, conv.ID)
+	}
+
+	chs, err := p.channelRepo.findByIDs(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("p.channelRepo.findByIDs: %w", err)
+	}
+
+	byID := make(map[string]*channel, len(chs))
+	for _, ch := range chs {
+		byID[ch.ID] = ch
+	}
+
+	var summaries []*channelSummary
+
+	for _, conv := range convs {
+		ch, ok := byID[conv.ID]
+		if !ok {
+			continue
+		}
+
+		cs := p.settings.get().channel(ch.ID)
+		cs.applyTo(ch)
+
+		month := time.Now().In(cs.location()).Format(monthLayout)
+
+		exs, err := p.expenditureRepo.list(ctx, ch.ID, month)
+		if err != nil {
+			return fmt.Errorf("p.expenditureRepo.list: %w", err)
+		}
+
+		for _, ex := range exs {
+			ex.in(cs.location())
+		}
+
+		s := summarize(ch, conv.Name, month, exs)
+
+		if s.carry, err = carryOver(ctx, newMonthTotals(p.expenditureRepo, ch.ID), ch, month); err != nil {
+			return fmt.Errorf("carryOver: %w", err)
+		}
+
+		summaries = append(summaries, s)
+	}
+
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].name < summaries[j].name })
+
+	l := langFor(ctx, p.userRepo, nil, ev.User)
+
+	r := &slack.ViewsPublishReq{
+		UserID: ev.User,
+		View:   homeView(l, summaries),
+	}
+
+	if err := p.slack.ViewsPublish(ctx, r); err != nil {
+		return fmt.Errorf("p.slack.ViewsPublish: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_progressBar(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		total  int64
+		budget int64
+		e      string
+	}{
+		"empty":     {0, 1000, "░░░░░░░░░░ 0%"},
+		"half":      {500, 1000, "▓▓▓▓▓░░░░░ 50%"},
+		"over":      {1500, 1000, "▓▓▓▓▓▓▓▓▓▓ 150%"},
+		"no budget": {500, 0, ""},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if a := progressBar(c.total, c.budget); a != c.e {
+				t.Errorf("progressBar(%d, %d) should be %q, but %q", c.total, c.budget, c.e, a)
+			}
+		})
+	}
+}
+
+func Test_summarize(t *testing.T) {
+	t.Parallel()
+
+	base := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	var exs []*expenditure
+
+	for i, c := range []string{"food", "food", "daily", "", "transport", "other", "food"} {
+		exs = append(exs, &expenditure{
+			TS:        strings.Repeat("1", i+1),
+			Amount:    int64(100 * (i + 1)),
+			Category:  c,
+			Timestamp: base.Add(time.Duration(i) * time.Hour),
+		})
+	}
+
+	s := summarize(&channel{ID: "ch1", Budget: 10000}, "general", "2026-10", exs)
+
+	if s.total != 2800 {
+		t.Errorf("incorrect total: %d", s.total)
+	}
+
+	// food: 100+200+700, other: 600, transport: 500
+	if len(s.categories) != homeTopCategories || s.categories[0].category != "food" || s.categories[0].amount != 1000 ||
+		s.categories[1].category != "other" || s.categories[2].category != "transport" {
+		t.Errorf("incorrect top categories: %+v", s.categories)
+	}
+
+	if len(s.recent) != homeRecent || s.recent[0].Amount != 700 || s.recent[4].Amount != 300 {
+		t.Errorf("incorrect recent expenditures: %v", s.recent)
+	}
+}
+
+func Test_homeView(t *testing.T) {
+	t.Parallel()
+
+	ch := &channel{ID: "ch1", Budget: 10000, Budgets: []budgetVersion{{From: "2026-11", Amount: 20000}}}
+
+	s := summarize(ch, "general", "2026-10", []*expenditure{
+		{TS: "1", Amount: 1200, Category: "food", Memo: "lunch", Timestamp: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)},
+	})
+
+	b, err := json.Marshal(homeView(langEN, []*channelSummary{s}))
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+
+	for _, want := range []string{`"type":"home"`, "#ch1", "¥8,800 left of ¥10,000", "10/19 ¥1,200 food lunch"} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("home view should contain %q: %s", want, b)
+		}
+	}
+
+	if b, _ := json.Marshal(homeView(langEN, nil)); !strings.Contains(string(b), "None of your channels") {
+		t.Errorf("home view without channels should guide to set budget: %s", b)
+	}
+}
+
+func Test_channelRepo_findByIDs(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fs := getFirestoreClient(t)
+	r := &channelRepo{fs}
+
+	defer flushStore(t)
+
+	for _, id := range []string{"ch1", "ch2"} {
+		if err := r.save(ctx, &channel{ID: id, Budget: 10000}); err != nil {
+			t.Fatalf("r.save: %v", err)
+		}
+	}
+
+	chs, err := r.findByIDs(ctx, []string{"ch2", "unknown"})
+	if err != nil || len(chs) != 1 || chs[0].ID != "ch2" || chs[0].Budget != 10000 {
+		t.Errorf("only budget channels in the IDs should be found: %v, %v", chs, err)
+	}
+
+	if chs, err := r.findByIDs(ctx, nil); err != nil || len(chs) != 0 {
+		t.Errorf("no IDs should find nothing: %v, %v", chs, err)
+	}
+}
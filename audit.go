@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/slack-go/slack"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// Audit entries are stored under each channel.
+	auditCollectionName = "audit"
+
+	defaultAuditEntries = 10
+	maxAuditEntries     = 50
+
+	// Entries are purged in batches not to read all of them at once.
+	auditPurgeBatchSize = 500
+)
+
+type auditAction string
+
+const (
+	auditChannelSaved        auditAction = "channel.saved"
+	auditExpenditureAdded    auditAction = "expenditure.added"
+	auditExpenditureUpdated  auditAction = "expenditure.updated"
+	auditExpenditureDeleted  auditAction = "expenditure.deleted"
+	auditExpenditureRestored auditAction = "expenditure.restored"
+)
+
+// auditEntry is an append-only record of a mutation.
+type auditEntry struct {
+	ID      string `firestore:"-"`
+	Channel string `firestore:"-"`
+	// Slack user ID of who did it. Empty means MoneySaver itself, e.g. on startup, and actorUnknown means
+	// someone Slack doesn't tell.
+	Actor  string      `firestore:"actor,omitempty"`
+	Action auditAction `firestore:"action"`
+	// Key of the expenditure. Empty for channels.
+	Target string `firestore:"target,omitempty"`
+	// Documents before and after the mutation, which are read as maps. Nil when there's no document.
+	Before interface{} `firestore:"before"`
+	After  interface{} `firestore:"after"`
+	// Slack event ID or trigger ID which caused it
+	EventID string    `firestore:"eventId,omitempty"`
+	At      time.Time `firestore:"at"`
+}
+
+// actorUnknown is recorded as the actor when Slack doesn't tell who did it.
+const actorUnknown = "unknown"
+
+// actorLabel returns the mention of the actor.
+func actorLabel(l lang, actor string) string {
+	switch actor {
+	case "":
+		return l.t(msgAuditSystem)
+	case actorUnknown:
+		return l.t(msgAuditUnknownActor)
+	}
+
+	return "<@" + actor + ">"
+}
+
+type auditContextKey struct{}
+
+type auditSource struct {
+	actor   string
+	eventID string
+}
+
+// withActor sets who causes mutations in ctx and the source event, which are recorded in audit entries.
+func withActor(ctx context.Context, actor, eventID string) context.Context {
+	return context.WithValue(ctx, auditContextKey{}, auditSource{actor: actor, eventID: eventID})
+}
+
+func auditSourceFrom(ctx context.Context) auditSource {
+	s, _ := ctx.Value(auditContextKey{}).(auditSource)
+	return s
+}
+
+func auditCollection(ctx context.Context, c *firestore.Client, chID string) *firestore.CollectionRef {
+	return teamCollection(ctx, c, collectionName).Doc(chID).Collection(auditCollectionName)
+}
+
+// recordAudit appends an entry of the mutation in the transaction.
+func recordAudit(
+	ctx context.Context, tx *firestore.Transaction, c *firestore.Client,
+	chID string, action auditAction, target string, before map[string]interface{}, after interface{},
+) error {
+	src := auditSourceFrom(ctx)
+
+	e := &auditEntry{
+		Actor:   src.actor,
+		Action:  action,
+		Target:  target,
+		Before:  nilIfEmpty(before),
+		After:   after,
+		EventID: src.eventID,
+		At:      time.Now(),
+	}
+
+	if err := tx.Create(auditCollection(ctx, c, chID).NewDoc(), e); err != nil {
+		return fmt.Errorf("tx.Create: %w", err)
+	}
+
+	return nil
+}
+
+// txData returns the data of the document in the transaction, or nil if it doesn't exist.
+func txData(tx *firestore.Transaction, ref *firestore.DocumentRef) (map[string]interface{}, error) {
+	doc, err := tx.Get(ref)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("tx.Get: %w", err)
+	}
+
+	return doc.Data(), nil
+}
+
+// nilIfEmpty keeps the absence of documents as null.
+func nilIfEmpty(d map[string]interface{}) interface{} {
+	if d == nil {
+		return nil
+	}
+
+	return d
+}
+
+// field returns the field of a document read from an entry.
+func field(d interface{}, k string) interface{} {
+	m, _ := d.(map[string]interface{})
+	return m[k]
+}
+
+// changedFields returns sorted names of fields which differ between before and after.
+func (e *auditEntry) changedFields() []string {
+	before, _ := e.Before.(map[string]interface{})
+	after, _ := e.After.(map[string]interface{})
+
+	keys := map[string]bool{}
+	for k := range before {
+		keys[k] = true
+	}
+
+	for k := range after {
+		keys[k] = true
+	}
+
+	var changed []string
+
+	for k := range keys {
+		if fmt.Sprint(before[k]) != fmt.Sprint(after[k]) {
+			changed = append(changed, k)
+		}
+	}
+
+	sort.Strings(changed)
+
+	return changed
+}
+
+type auditRepo struct {
+	*firestore.Client
+}
+
+// list returns the latest entries of the channel.
+func (r *auditRepo) list(ctx context.Context, chID string, limit int) (_ []*auditEntry, err error) {
+	ctx, done := instrumentStorage(ctx, "audit.list")
+	defer done(&err)
+
+	docs, err := auditCollection(ctx, r.Client, chID).OrderBy("at", firestore.Desc).Limit(limit).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("auditCollection.Documents.GetAll: %w", err)
+	}
+
+	es := make([]*auditEntry, 0, len(docs))
+
+	for _, doc := range docs {
+		var e auditEntry
+		if err := doc.DataTo(&e); err != nil {
+			return nil, fmt.Errorf("doc.DataTo: %w", err)
+		}
+
+		e.ID = doc.Ref.ID
+		e.Channel = chID
+		es = append(es, &e)
+	}
+
+	return es, nil
+}
+
+// purge deletes entries of all channels recorded before the time, and returns how many are deleted.
+func (r *auditRepo) purge(ctx context.Context, before time.Time) (n int, err error) {
+	ctx, done := instrumentStorage(ctx, "audit.purge")
+	defer done(&err)
+
+	q := r.CollectionGroup(auditCollectionName).Where("at", "<", before).Limit(auditPurgeBatchSize)
+
+	for {
+		docs, err := q.Documents(ctx).GetAll()
+		if err != nil {
+			return n, fmt.Errorf("q.Documents.GetAll: %w", err)
+		}
+
+		deleted, err := r.deleteAll(ctx, docs)
+		n += deleted
+
+		// Stop on errors not to read the same entries again.
+		if err != nil || len(docs) < auditPurgeBatchSize {
+			return n, err
+		}
+	}
+}
+
+// deleteAll deletes the entries, and returns how many are deleted.
+func (r *auditRepo) deleteAll(ctx context.Context, docs []*firestore.DocumentSnapshot) (n int, err error) {
+	if len(docs) == 0 {
+		return 0, nil
+	}
+
+	bw := r.BulkWriter(ctx)
+
+	jobs := make([]*firestore.BulkWriterJob, 0, len(docs))
+
+	for _, doc := range docs {
+		job, err := bw.Delete(doc.Ref)
+		if err != nil {
+			bw.End()
+			return 0, fmt.Errorf("bw.Delete: %w", err)
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	bw.End()
+
+	var errs []error
+
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			errs = append(errs, err)
+		} else {
+			n++
+		}
+	}
+
+	return n, errors.Join(errs...)
+}
+
+// auditPurger deletes audit entries older than the retention.
+type auditPurger struct {
+	repo      *auditRepo
+	retention time.Duration
+}
+
+// run purges entries periodically until ctx is done.
+func (p *auditPurger) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := p.repo.purge(ctx, time.Now().Add(-p.retention))
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to purge audit entries", slog.Int("deleted", n), slog.Any("err", err))
+		} else if n > 0 {
+			logger.InfoContext(ctx, "purged audit entries", slog.Int("deleted", n))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// processAudit shows the latest audit entries of the channel to admins.
+func (p *commandProcessor) processAudit(
+	ctx context.Context, c slack.SlashCommand, l lang, ch *channel, args []string,
+) (*slack.Msg, error) {
+	limit := defaultAuditEntries
+
+	if len(args) > 1 {
+		return &slack.Msg{Text: l.t(msgAuditUsage)}, nil
+	}
+
+	if len(args) == 1 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 || n > maxAuditEntries {
+			return &slack.Msg{Text: l.t(msgAuditUsage)}, nil
+		}
+
+		limit = n
+	}
+
+	if ch == nil {
+		return &slack.Msg{Text: l.t(msgSetBudgetFirst)}, nil
+	}
+
+	if !ch.allows(c.UserID, roleAdmins) {
+		return notAllowed(l, roleAdmins), nil
+	}
+
+	es, err := p.auditRepo.list(ctx, ch.ID, limit)
+	if err != nil {
+		return nil, wrap(http.StatusInternalServerError, "p.auditRepo.list: %w", err)
+	}
+
+	if len(es) == 0 {
+		return &slack.Msg{Text: l.t(msgAuditEmpty)}, nil
+	}
+
+	loc := p.settings.get().channel(ch.ID).location()
+
+	lines := []string{l.t(msgAuditTitle)}
+	for _, e := range es {
+		lines = append(lines, "• "+formatAuditEntry(l, e, loc))
+	}
+
+	return &slack.Msg{Text: strings.Join(lines, "\n")}, nil
+}
+
+func formatAuditEntry(l lang, e *auditEntry, loc *time.Location) string {
+	actor := actorLabel(l, e.Actor)
+
+	var text string
+
+	switch e.Action {
+	case auditChannelSaved:
+		text = l.t(msgAuditChannelSaved, actor, strings.Join(e.changedFields(), ", "))
+	case auditExpenditureAdded:
+		text = l.t(msgAuditExpenditureAdded, actor, field(e.After, "amount"), e.Target)
+	case auditExpenditureUpdated:
+		text = l.t(msgAuditExpenditureUpdated, actor, e.Target, strings.Join(e.changedFields(), ", "))
+	case auditExpenditureDeleted:
+		text = l.t(msgAuditExpenditureDeleted, actor, field(e.Before, "amount"), e.Target)
+	case auditExpenditureRestored:
+		text = l.t(msgAuditExpenditureRestored, actor, field(e.After, "amount"), e.Target)
+	default:
+		text = actor + " " + string(e.Action)
+	}
+
+	return e.At.In(loc).Format("2006-01-02 15:04") + " " + text
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack/slackevents"
+)
+
+func Test_auditEntry_changedFields(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		before interface{}
+		after  interface{}
+		want   []string
+	}{
+		"created": {
+			before: nil,
+			after:  map[string]interface{}{"budget": int64(1000), "id": "C1"},
+			want:   []string{"budget", "id"},
+		},
+		"updated": {
+			before: map[string]interface{}{"budget": int64(1000), "id": "C1", "owners": []interface{}{"U1"}},
+			after:  map[string]interface{}{"budget": int64(2000), "id": "C1", "owners": []interface{}{"U1"}},
+			want:   []string{"budget"},
+		},
+		"deleted": {
+			before: map[string]interface{}{"amount": int64(1000)},
+			after:  nil,
+			want:   []string{"amount"},
+		},
+		"unchanged": {
+			before: map[string]interface{}{"budget": int64(1000)},
+			after:  map[string]interface{}{"budget": int64(1000)},
+			want:   nil,
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			e := &auditEntry{Before: c.before, After: c.after}
+			if got := e.changedFields(); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("changedFields() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func Test_messageActor(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		ev   *slackevents.MessageEvent
+		want string
+	}{
+		"posted":  {ev: &slackevents.MessageEvent{User: "U1"}, want: "U1"},
+		"edited":  {ev: &slackevents.MessageEvent{SubType: "message_changed", Message: &slackevents.MessageEvent{User: "U1"}}, want: "U1"},
+		"deleted": {ev: &slackevents.MessageEvent{SubType: "message_deleted", PreviousMessage: &slackevents.MessageEvent{User: "U1"}}, want: actorUnknown},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := messageActor(c.ev); got != c.want {
+				t.Errorf("messageActor() = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func Test_formatAuditEntry(t *testing.T) {
+	t.Parallel()
+
+	at := time.Date(2026, 10, 1, 0, 30, 0, 0, time.UTC)
+	jst := time.FixedZone("Asia/Tokyo", 9*60*60)
+
+	cases := map[string]struct {
+		entry *auditEntry
+		want  string
+	}{
+		"channel": {
+			entry: &auditEntry{
+				Actor:  "U1",
+				Action: auditChannelSaved,
+				Before: map[string]interface{}{"budget": int64(1000)},
+				After:  map[string]interface{}{"budget": int64(2000)},
+				At:     at,
+			},
+			want: "2026-10-01 09:30 <@U1> changed the channel settings (budget)",
+		},
+		"added by system": {
+			entry: &auditEntry{
+				Action: auditExpenditureAdded,
+				Target: "2026-10/123.456",
+				After:  map[string]interface{}{"amount": int64(1000)},
+				At:     at,
+			},
+			want: "2026-10-01 09:30 MoneySaver recorded an expenditure of 1000 (2026-10/123.456)",
+		},
+		"updated": {
+			entry: &auditEntry{
+				Actor:  "U1",
+				Action: auditExpenditureUpdated,
+				Target: "2026-10/123.456",
+				Before: map[string]interface{}{"amount": int64(1000)},
+				After:  map[string]interface{}{"amount": int64(1500)},
+				At:     at,
+			},
+			want: "2026-10-01 09:30 <@U1> updated the expenditure 2026-10/123.456 (amount)",
+		},
+		"deleted": {
+			entry: &auditEntry{
+				Actor:  "U1",
+				Action: auditExpenditureDeleted,
+				Target: "2026-10/123.456",
+				Before: map[string]interface{}{"amount": int64(1000)},
+				At:     at,
+			},
+			want: "2026-10-01 09:30 <@U1> deleted an expenditure of 1000 (2026-10/123.456)",
+		},
+		"deleted by unknown": {
+			entry: &auditEntry{
+				Actor:  actorUnknown,
+				Action: auditExpenditureDeleted,
+				Target: "2026-10/123.456",
+				Before: map[string]interface{}{"amount": int64(1000)},
+				At:     at,
+			},
+			want: "2026-10-01 09:30 Someone deleted an expenditure of 1000 (2026-10/123.456)",
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := formatAuditEntry(langEN, c.entry, jst); got != c.want {
+				t.Errorf("formatAuditEntry() = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func Test_auditRepo(t *testing.T) {
+	t.Parallel()
+
+	fs := getFirestoreClient(t)
+	ctx := withActor(context.Background(), "U1", "Ev0001")
+
+	defer flushStore(t)
+
+	chRepo := &channelRepo{fs}
+	exRepo := &expenditureRepo{fs}
+	repo := &auditRepo{fs}
+
+	ch := &channel{ID: "CAUDIT", Budget: 1000}
+	if err := chRepo.save(ctx, ch); err != nil {
+		t.Fatalf("chRepo.save: %v", err)
+	}
+
+	ch.Budget = 2000
+	if err := chRepo.save(ctx, ch); err != nil {
+		t.Fatalf("chRepo.save: %v", err)
+	}
+
+	ex := &expenditure{Channel: ch.ID, TS: "1790000000.000100", Amount: 300, User: "U1"}
+	if err := exRepo.add(ctx, ex); err != nil {
+		t.Fatalf("exRepo.add: %v", err)
+	}
+
+	ex.Amount = 400
+	if err := exRepo.add(ctx, ex); err != nil {
+		t.Fatalf("exRepo.add: %v", err)
+	}
+
+	if err := exRepo.delete(ctx, ex); err != nil {
+		t.Fatalf("exRepo.delete: %v", err)
+	}
+
+	if err := exRepo.delete(ctx, ex); err == nil {
+		t.Errorf("deleting a missing expenditure should fail")
+	}
+
+	es, err := repo.list(ctx, ch.ID, maxAuditEntries)
+	if err != nil {
+		t.Fatalf("repo.list: %v", err)
+	}
+
+	var actions []auditAction
+	for _, e := range es {
+		actions = append(actions, e.Action)
+
+		if e.Actor != "U1" || e.EventID != "Ev0001" {
+			t.Errorf("incorrect source: %+v", e)
+		}
+	}
+
+	want := []auditAction{
+		auditExpenditureDeleted, auditExpenditureUpdated, auditExpenditureAdded, auditChannelSaved, auditChannelSaved,
+	}
+	if !reflect.DeepEqual(actions, want) {
+		t.Errorf("actions = %v, want %v", actions, want)
+	}
+
+	if got := es[1].changedFields(); !reflect.DeepEqual(got, []string{"amount"}) {
+		t.Errorf("changed fields of the update = %v", got)
+	}
+
+	if es[4].Before != nil {
+		t.Errorf("first save should have no before: %v", es[4].Before)
+	}
+
+	n, err := repo.purge(ctx, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("repo.purge: %v", err)
+	}
+
+	if n != len(es) {
+		t.Errorf("purged %d entries, want %d", n, len(es))
+	}
+
+	if es, err := repo.list(ctx, ch.ID, maxAuditEntries); err != nil || len(es) != 0 {
+		t.Errorf("entries should be purged: %v, %v", es, err)
+	}
+}
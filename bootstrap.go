@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// changedLimits returns limits which are added or changed from the applied ones.
+func changedLimits(applied, limits map[string]int64) map[string]int64 {
+	changed := make(map[string]int64, len(limits))
+
+	for id, budget := range limits {
+		if b, ok := applied[id]; !ok || b != budget {
+			changed[id] = budget
+		}
+	}
+
+	return changed
+}
+
+// bootstrapLimits seeds or updates budgets of channels declared in LIMITS from this month on.
+// Channels not in limits are left as they are.
+// With dryRun, it only logs the changes.
+func bootstrapLimits(ctx context.Context, r *channelRepo, limits map[string]int64, dryRun bool) error {
+	ids := make([]string, 0, len(limits))
+	for id := range limits {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	now := time.Now()
+	month := now.Format(monthLayout)
+
+	for _, id := range ids {
+		budget := limits[id]
+
+		ch, err := r.findByID(ctx, id)
+		if errors.Is(err, errNotFound) {
+			logger.InfoContext(ctx, "LIMITS: create channel",
+				slog.String("channel", id), slog.Int64("budget", budget), slog.Bool("dryRun", dryRun))
+
+			ch = &channel{ID: id}
+		} else if err != nil {
+			return fmt.Errorf("r.findByID: %w", err)
+		} else if ch.budgetFor(month) == budget {
+			logger.InfoContext(ctx, "LIMITS: channel is up to date", slog.String("channel", id), slog.Bool("dryRun", dryRun))
+
+			continue
+		} else {
+			logger.InfoContext(ctx, "LIMITS: update budget of channel",
+				slog.String("channel", id), slog.Int64("from", ch.budgetFor(month)), slog.Int64("to", budget), slog.Bool("dryRun", dryRun))
+		}
+
+		if dryRun {
+			continue
+		}
+
+		ch.setBudget(budget, month, false, now)
+
+		if err := r.save(ctx, ch); err != nil {
+			return fmt.Errorf("r.save: %w", err)
+		}
+	}
+
+	return nil
+}
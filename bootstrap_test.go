@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_changedLimits(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		applied map[string]int64
+		limits  map[string]int64
+		e       map[string]int64
+	}{
+		"startup":   {applied: nil, limits: map[string]int64{"C1": 1000}, e: map[string]int64{"C1": 1000}},
+		"unchanged": {applied: map[string]int64{"C1": 1000}, limits: map[string]int64{"C1": 1000}, e: map[string]int64{}},
+		"changed": {
+			applied: map[string]int64{"C1": 1000, "C2": 2000},
+			limits:  map[string]int64{"C1": 1500, "C2": 2000, "C3": 3000},
+			e:       map[string]int64{"C1": 1500, "C3": 3000},
+		},
+		"removed": {applied: map[string]int64{"C1": 1000}, limits: map[string]int64{}, e: map[string]int64{}},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if a := changedLimits(c.applied, c.limits); !reflect.DeepEqual(a, c.e) {
+				t.Errorf("expected %v, but %v", c.e, a)
+			}
+		})
+	}
+}
+
+func Test_bootstrapLimits(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fs := getFirestoreClient(t)
+	r := &channelRepo{fs}
+
+	defer flushStore(t)
+
+	if err := r.save(ctx, &channel{ID: "ch1", Budget: 1000, Lang: "en"}); err != nil {
+		t.Fatalf("r.save: %v", err)
+	}
+
+	limits := map[string]int64{"ch1": 2000, "ch2": 3000}
+
+	if err := bootstrapLimits(ctx, r, limits, true); err != nil {
+		t.Fatalf("bootstrapLimits: %v", err)
+	}
+
+	month := time.Now().Format(monthLayout)
+
+	if ch, err := r.findByID(ctx, "ch1"); err != nil || ch.budgetFor(month) != 1000 {
+		t.Errorf("dry run should not update ch1: %v, %v", ch, err)
+	}
+
+	if err := bootstrapLimits(ctx, r, limits, false); err != nil {
+		t.Fatalf("bootstrapLimits: %v", err)
+	}
+
+	for id, budget := range limits {
+		ch, err := r.findByID(ctx, id)
+		if err != nil {
+			t.Fatalf("r.findByID: %v", err)
+		}
+
+		if ch.budgetFor(month) != budget {
+			t.Errorf("budget of %s should be %d, but %d", id, budget, ch.budgetFor(month))
+		}
+	}
+
+	if ch, _ := r.findByID(ctx, "ch1"); ch.Lang != "en" || ch.budgetFor("2000-01") != 1000 {
+		t.Errorf("bootstrap should keep other fields and past budgets of ch1: %+v", ch)
+	}
+}
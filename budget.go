@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// rolloverMode decides what is carried over from the previous month.
+type rolloverMode string
+
+const (
+	rolloverNone    rolloverMode = "none"
+	rolloverSurplus rolloverMode = "surplus"
+	rolloverDeficit rolloverMode = "deficit"
+	rolloverBoth    rolloverMode = "both"
+
+	// Months looked back to compute the amount carried over
+	rolloverMonths = 12
+
+	// Months read at once by monthTotals
+	monthTotalsConcurrency = 12
+)
+
+var rolloverModes = []rolloverMode{rolloverNone, rolloverSurplus, rolloverDeficit, rolloverBoth}
+
+func parseRolloverMode(s string) (rolloverMode, bool) {
+	for _, m := range rolloverModes {
+		if string(m) == s {
+			return m, true
+		}
+	}
+
+	return "", false
+}
+
+func (ch *channel) rollover() rolloverMode {
+	if ch.Rollover == "" {
+		return rolloverNone
+	}
+
+	return ch.Rollover
+}
+
+// budgetVersion is a monthly budget which takes effect from a month.
+type budgetVersion struct {
+	From   string `firestore:"from"`
+	Amount int64  `firestore:"amount"`
+	// Overrides the budget of only the From month.
+	Only  bool      `firestore:"only,omitempty"`
+	SetAt time.Time `firestore:"setAt"`
+}
+
+// budgetFor returns the budget of the month, which is the override of the month,
+// the latest version from the month or before, or the budget before the history in this order.
+func (ch *channel) budgetFor(month string) int64 {
+	var (
+		budget   = ch.Budget
+		from     string
+		override *int64
+	)
+
+	for i := range ch.Budgets {
+		v := &ch.Budgets[i]
+
+		if v.Only {
+			if v.From == month {
+				override = &v.Amount
+			}
+
+			continue
+		}
+
+		if v.From <= month && v.From >= from {
+			budget, from = v.Amount, v.From
+		}
+	}
+
+	if override != nil {
+		return *override
+	}
+
+	return budget
+}
+
+// setBudget sets the budget from the month on, or of only the month.
+// Budgets of other months are kept.
+func (ch *channel) setBudget(amount int64, month string, only bool, now time.Time) {
+	// Months before a new channel have no expenditures, so it's the budget of all months.
+	if ch.Budget == 0 && len(ch.Budgets) == 0 && !only {
+		ch.Budget = amount
+	}
+
+	ch.Budgets = append(ch.Budgets, budgetVersion{From: month, Amount: amount, Only: only, SetAt: now})
+}
+
+// parseBudgetFlags removes `--from YYYY-MM` or `--only YYYY-MM` from args.
+// It returns empty month without flags.
+func parseBudgetFlags(args []string) ([]string, string, bool, error) {
+	rest := make([]string, 0, len(args))
+
+	var (
+		month string
+		only  bool
+	)
+
+	for i := 0; i < len(args); i++ {
+		if args[i] != "--from" && args[i] != "--only" {
+			rest = append(rest, args[i])
+
+			continue
+		}
+
+		if month != "" || i+1 >= len(args) {
+			return nil, "", false, fmt.Errorf("invalid flags: %v", args)
+		}
+
+		if _, err := time.Parse(monthLayout, args[i+1]); err != nil {
+			return nil, "", false, fmt.Errorf("invalid month: %q", args[i+1])
+		}
+
+		month, only = args[i+1], args[i] == "--only"
+		i++
+	}
+
+	return rest, month, only, nil
+}
+
+// carry returns the amount carried over into the next month from the balance of a month.
+// Surplus is positive and deficit is negative.
+func (ch *channel) carry(balance int64) int64 {
+	switch m := ch.rollover(); {
+	case balance > 0 && (m == rolloverSurplus || m == rolloverBoth):
+		if ch.RolloverCap > 0 && balance > ch.RolloverCap {
+			return ch.RolloverCap
+		}
+
+		return balance
+	case balance < 0 && (m == rolloverDeficit || m == rolloverBoth):
+		if ch.RolloverCap > 0 && balance < -ch.RolloverCap {
+			return -ch.RolloverCap
+		}
+
+		return balance
+	}
+
+	return 0
+}
+
+// previousMonths returns n months before the month, oldest first.
+func previousMonths(month string, n int) ([]string, error) {
+	m, err := time.Parse(monthLayout, month)
+	if err != nil {
+		return nil, fmt.Errorf("time.Parse: %w", err)
+	}
+
+	months := make([]string, 0, n)
+	for i := n; i > 0; i-- {
+		months = append(months, m.AddDate(0, -i, 0).Format(monthLayout))
+	}
+
+	return months, nil
+}
+
+// monthTotals memoizes totals of months of a channel, so that a reply reads each month once
+// for the amount carried over and positions of periods.
+type monthTotals struct {
+	repo *expenditureRepo
+	chID string
+
+	// By month and category
+	mu    sync.Mutex
+	cache map[[2]string]monthTotal
+}
+
+func newMonthTotals(r *expenditureRepo, chID string) *monthTotals {
+	return &monthTotals{repo: r, chID: chID, cache: map[[2]string]monthTotal{}}
+}
+
+// get returns the total of the month, of only the category if it's not empty.
+func (t *monthTotals) get(ctx context.Context, month, category string) (monthTotal, error) {
+	t.mu.Lock()
+	mt, ok := t.cache[[2]string{month, category}]
+	t.mu.Unlock()
+
+	if ok {
+		return mt, nil
+	}
+
+	mt, err := t.repo.sum(ctx, t.chID, month, category)
+	if err != nil {
+		return monthTotal{}, fmt.Errorf("t.repo.sum: %w", err)
+	}
+
+	t.mu.Lock()
+	t.cache[[2]string{month, category}] = mt
+	t.mu.Unlock()
+
+	return mt, nil
+}
+
+// prefetch reads totals of the months concurrently, so that a reply doesn't wait for them one by one.
+func (t *monthTotals) prefetch(ctx context.Context, months []string, category string) error {
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(monthTotalsConcurrency)
+
+	for _, m := range months {
+		m := m
+
+		g.Go(func() error {
+			_, err := t.get(ctx, m, category)
+
+			return err
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return fmt.Errorf("g.Wait: %w", err)
+	}
+
+	return nil
+}
+
+// carryOver returns the amount carried over into the month from previous months.
+// It goes back up to rolloverMonths and starts from the first month with expenditures,
+// so months before the channel was used are not counted as surplus.
+func carryOver(ctx context.Context, totals *monthTotals, ch *channel, month string) (int64, error) {
+	if ch.rollover() == rolloverNone {
+		return 0, nil
+	}
+
+	months, err := previousMonths(month, rolloverMonths)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := totals.prefetch(ctx, months, ""); err != nil {
+		return 0, fmt.Errorf("totals.prefetch: %w", err)
+	}
+
+	var (
+		carry   int64
+		started bool
+	)
+
+	for _, m := range months {
+		mt, err := totals.get(ctx, m, "")
+		if err != nil {
+			return 0, err
+		}
+
+		if !started && mt.count == 0 {
+			continue
+		}
+
+		started = true
+
+		carry = ch.carry(ch.budgetFor(m) + carry - mt.amount)
+	}
+
+	return carry, nil
+}
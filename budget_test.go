@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_channel_carry(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		ch      *channel
+		balance int64
+		e       int64
+	}{
+		"none":            {ch: &channel{}, balance: 1000, e: 0},
+		"surplus":         {ch: &channel{Rollover: rolloverSurplus}, balance: 1000, e: 1000},
+		"surplus deficit": {ch: &channel{Rollover: rolloverSurplus}, balance: -1000, e: 0},
+		"deficit":         {ch: &channel{Rollover: rolloverDeficit}, balance: -1000, e: -1000},
+		"deficit surplus": {ch: &channel{Rollover: rolloverDeficit}, balance: 1000, e: 0},
+		"both surplus":    {ch: &channel{Rollover: rolloverBoth}, balance: 1000, e: 1000},
+		"both deficit":    {ch: &channel{Rollover: rolloverBoth}, balance: -1000, e: -1000},
+		"surplus cap":     {ch: &channel{Rollover: rolloverBoth, RolloverCap: 500}, balance: 1000, e: 500},
+		"deficit cap":     {ch: &channel{Rollover: rolloverBoth, RolloverCap: 500}, balance: -1000, e: -500},
+		"under cap":       {ch: &channel{Rollover: rolloverBoth, RolloverCap: 5000}, balance: 1000, e: 1000},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if a := c.ch.carry(c.balance); a != c.e {
+				t.Errorf("expected %d, but %d", c.e, a)
+			}
+		})
+	}
+}
+
+func Test_previousMonths(t *testing.T) {
+	t.Parallel()
+
+	months, err := previousMonths("2026-02", 3)
+	if err != nil {
+		t.Fatalf("previousMonths: %v", err)
+	}
+
+	if e := []string{"2025-11", "2025-12", "2026-01"}; !reflect.DeepEqual(months, e) {
+		t.Errorf("expected %v, but %v", e, months)
+	}
+}
+
+func Test_carryOver(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fs := getFirestoreClient(t)
+
+	defer flushStore(t)
+
+	p := &eventProcessor{expenditureRepo: &expenditureRepo{fs}}
+
+	for _, ex := range []*expenditure{
+		{Channel: "ch1", TS: "1", Amount: 7000, Timestamp: time.Date(2026, 8, 10, 0, 0, 0, 0, time.UTC)},
+		{Channel: "ch1", TS: "2", Amount: 12000, Timestamp: time.Date(2026, 9, 10, 0, 0, 0, 0, time.UTC)},
+	} {
+		if err := p.expenditureRepo.add(ctx, ex); err != nil {
+			t.Fatalf("p.expenditureRepo.add: %v", err)
+		}
+	}
+
+	// Deleted expenditures are not counted.
+	deleted := &expenditure{Channel: "ch1", TS: "3", Amount: 5000, Timestamp: time.Date(2026, 9, 20, 0, 0, 0, 0, time.UTC)}
+	if err := p.expenditureRepo.add(ctx, deleted); err != nil {
+		t.Fatalf("p.expenditureRepo.add: %v", err)
+	}
+
+	if err := p.expenditureRepo.delete(ctx, deleted); err != nil {
+		t.Fatalf("p.expenditureRepo.delete: %v", err)
+	}
+
+	cases := map[string]struct {
+		ch *channel
+		e  int64
+	}{
+		// August: 10000 - 7000 = 3000, September: 10000 + 3000 - 12000 = 1000
+		"both": {ch: &channel{ID: "ch1", Budget: 10000, Rollover: rolloverBoth}, e: 1000},
+		// August: 3000, September: 10000 + 3000 - 12000 = 1000
+		"surplus": {ch: &channel{ID: "ch1", Budget: 10000, Rollover: rolloverSurplus}, e: 1000},
+		// August: 0, September: 10000 - 12000 = -2000
+		"deficit": {ch: &channel{ID: "ch1", Budget: 10000, Rollover: rolloverDeficit}, e: -2000},
+		// August: 2000, September: 10000 + 2000 - 12000 = 0
+		"cap":  {ch: &channel{ID: "ch1", Budget: 10000, Rollover: rolloverBoth, RolloverCap: 2000}, e: 0},
+		"none": {ch: &channel{ID: "ch1", Budget: 10000}, e: 0},
+	}
+
+	for name, c := range cases {
+		if a, err := carryOver(ctx, newMonthTotals(p.expenditureRepo, "ch1"), c.ch, "2026-10"); err != nil || a != c.e {
+			t.Errorf("%s: expected %d, but %d, %v", name, c.e, a, err)
+		}
+	}
+}
+
+func Test_channel_budgetFor(t *testing.T) {
+	t.Parallel()
+
+	ch := &channel{
+		Budget: 100000,
+		Budgets: []budgetVersion{
+			{From: "2026-10", Amount: 120000},
+			{From: "2026-12", Amount: 150000, Only: true},
+			{From: "2027-03", Amount: 80000},
+			{From: "2026-10", Amount: 130000},
+		},
+	}
+
+	cases := map[string]int64{
+		"2026-09": 100000,
+		"2026-10": 130000,
+		"2026-11": 130000,
+		"2026-12": 150000,
+		"2027-01": 130000,
+		"2027-03": 80000,
+	}
+
+	for month, e := range cases {
+		if a := ch.budgetFor(month); a != e {
+			t.Errorf("%s: expected %d, but %d", month, e, a)
+		}
+	}
+}
+
+func Test_channel_setBudget(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+
+	ch := &channel{}
+	ch.setBudget(100000, "2026-10", false, now)
+
+	if ch.budgetFor("2026-01") != 100000 {
+		t.Errorf("budget of a new channel should be of all months: %+v", ch)
+	}
+
+	ch.setBudget(150000, "2026-12", true, now)
+	ch.setBudget(120000, "2027-01", false, now)
+
+	for month, e := range map[string]int64{"2026-09": 100000, "2026-12": 150000, "2027-01": 120000} {
+		if a := ch.budgetFor(month); a != e {
+			t.Errorf("%s: expected %d, but %d", month, e, a)
+		}
+	}
+}
+
+func Test_parseBudgetFlags(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		args    []string
+		rest    []string
+		month   string
+		only    bool
+		wantErr bool
+	}{
+		"no flags":      {args: []string{"1000", "JPY"}, rest: []string{"1000", "JPY"}},
+		"from":          {args: []string{"150000", "--from", "2026-12"}, rest: []string{"150000"}, month: "2026-12"},
+		"only":          {args: []string{"--only", "2026-12", "150000"}, rest: []string{"150000"}, month: "2026-12", only: true},
+		"no month":      {args: []string{"150000", "--from"}, wantErr: true},
+		"invalid month": {args: []string{"150000", "--from", "2026-13"}, wantErr: true},
+		"both":          {args: []string{"1", "--from", "2026-12", "--only", "2026-12"}, wantErr: true},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rest, month, only, err := parseBudgetFlags(c.args)
+			if c.wantErr != (err != nil) {
+				t.Fatalf("incorrect error: %v", err)
+			}
+
+			if !c.wantErr && (!reflect.DeepEqual(rest, c.rest) || month != c.month || only != c.only) {
+				t.Errorf("incorrect result: %v, %s, %v", rest, month, only)
+			}
+		})
+	}
+}
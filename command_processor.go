@@ -2,45 +2,342 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/slack-go/slack"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+type commandHandler func(p *commandProcessor, ctx context.Context, c slack.SlashCommand, l lang, ch *channel, args []string) (*slack.Msg, error)
+
+// commandHandlers are subcommands of /moneysaver.
+var commandHandlers = map[string]commandHandler{
+	"set":         (*commandProcessor).processSet,
+	"budgets":     (*commandProcessor).processBudgets,
+	"rate":        (*commandProcessor).processRate,
+	"lang":        (*commandProcessor).processLang,
+	"add":         (*commandProcessor).processAdd,
+	"rollover":    (*commandProcessor).processRollover,
+	"period":      (*commandProcessor).processPeriod,
+	"recurring":   (*commandProcessor).processRecurring,
+	"permissions": (*commandProcessor).processPermissions,
+	"audit":       (*commandProcessor).processAudit,
+	"trash":       (*commandProcessor).processTrash,
+}
+
+// commandName returns the subcommand name for metrics.
+func commandName(text string) string {
+	args := strings.Fields(text)
+	if len(args) == 0 {
+		return "none"
+	}
+
+	if _, ok := commandHandlers[args[0]]; !ok {
+		return "unknown"
+	}
+
+	return args[0]
+}
+
 type commandProcessor struct {
 	channelRepo *channelRepo
+	userRepo    *userRepo
+	settings    *settingsStore
+	// Aggregates spending against period budgets
+	expenditureRepo *expenditureRepo
+	// Recurring items of channels
+	recurringRepo *recurringRepo
+	// Validates currencies of recurring items
+	rates rateTable
+	// Opens modals
+	interactions *interactionProcessor
+	// Reads audit logs of channels
+	auditRepo *auditRepo
+}
+
+func (p *commandProcessor) process(ctx context.Context, c slack.SlashCommand) (_ *slack.Msg, err error) {
+	defer observeCommand(commandName(c.Text), time.Now(), &err)
+
+	ctx, end := startSpan(ctx, "processCommand", attribute.String("command", commandName(c.Text)))
+	defer end(&err)
+
+	ctx = withActor(ctx, c.UserID, c.TriggerID)
+
+	ch, err := p.channelRepo.findByID(ctx, c.ChannelID)
+	if errors.Is(err, errNotFound) {
+		ch = nil
+	} else if err != nil {
+		return nil, wrap(http.StatusInternalServerError, "p.channelRepo.findByID: %w", err)
+	}
+
+	// Don't modify ch with the config file since it may be saved.
+	view := ch
+	if ch != nil {
+		v := *ch
+		p.settings.get().channel(ch.ID).applyTo(&v)
+		view = &v
+	}
+
+	l := langFor(ctx, p.userRepo, view, c.UserID)
+
+	args := strings.Fields(c.Text)
+	if len(args) == 0 {
+		return &slack.Msg{Text: l.t(msgUsage)}, nil
+	}
+
+	h, ok := commandHandlers[args[0]]
+	if !ok {
+		return &slack.Msg{Text: l.t(msgUsage)}, nil
+	}
+
+	return h(p, ctx, c, l, ch, args[1:])
 }
 
-func (p *commandProcessor) process(ctx context.Context, c slack.SlashCommand) (*slack.Msg, error) {
-	args := strings.Split(c.Text, " ")
-	if len(args) != 2 || args[0] != "set" {
-		return &slack.Msg{Text: "Invalid command format. Usage: `/moneysaver set 1000"}, nil
+// processSet sets the budget from this month on, or from the month of `--from` or of only the month of `--only`.
+// The change is announced in the channel.
+func (p *commandProcessor) processSet(
+	ctx context.Context, c slack.SlashCommand, l lang, ch *channel, args []string,
+) (*slack.Msg, error) {
+	if !ch.allows(c.UserID, ch.CanSetBudget) {
+		return notAllowed(l, ch.CanSetBudget), nil
+	}
+
+	args, month, only, err := parseBudgetFlags(args)
+	if err != nil {
+		return &slack.Msg{Text: l.t(msgInvalidMonth)}, nil
+	}
+
+	if len(args) != 1 && len(args) != 2 {
+		return &slack.Msg{Text: l.t(msgUsage)}, nil
 	}
 
-	budget, err := strconv.ParseInt(args[1], 10, 64)
+	budget, err := strconv.ParseInt(args[0], 10, 64)
 	if err != nil {
-		return &slack.Msg{Text: "Budget must be an integer."}, nil
+		return &slack.Msg{Text: l.t(msgBudgetNotInteger)}, nil
 	}
 
-	if err := p.setBudget(ctx, c.ChannelID, budget); err != nil {
+	var cur string
+	if len(args) == 2 {
+		cur = strings.ToUpper(args[1])
+		if !knownCurrency(cur) {
+			return &slack.Msg{Text: l.t(msgInvalidCurrency)}, nil
+		}
+	}
+
+	now := time.Now().In(p.settings.get().channel(c.ChannelID).location())
+
+	if month == "" {
+		month = now.Format(monthLayout)
+	}
+
+	var prev int64
+	if ch != nil {
+		prev = ch.budgetFor(month)
+	}
+
+	updated, err := p.setBudget(ctx, c.ChannelID, c.UserID, budget, cur, month, only, now)
+	if err != nil {
 		return nil, wrap(http.StatusInternalServerError, "p.setBudget: %w", err)
 	}
 
-	return &slack.Msg{Text: "Set budget to #" + c.ChannelName}, nil
+	text := l.t(msgBudgetSetFrom, c.ChannelName, month)
+	if only {
+		text = l.t(msgBudgetSetOnly, c.ChannelName, month)
+	}
+
+	text += "\n" + l.t(msgBudgetChangedBy, c.UserID,
+		humanizeIn(prev, updated.currency()), humanizeIn(budget, updated.currency()))
+
+	return &slack.Msg{ResponseType: slack.ResponseTypeInChannel, Text: text}, nil
+}
+
+// processBudgets lists the history of budgets.
+func (p *commandProcessor) processBudgets(
+	_ context.Context, _ slack.SlashCommand, l lang, ch *channel, args []string,
+) (*slack.Msg, error) {
+	if len(args) != 0 {
+		return &slack.Msg{Text: l.t(msgUsage)}, nil
+	}
+
+	if ch == nil {
+		return &slack.Msg{Text: l.t(msgSetBudgetFirst)}, nil
+	}
+
+	cur := ch.currency()
+
+	lines := []string{l.t(msgBudgetHistoryTitle), "• " + l.t(msgBudgetHistoryBase, humanizeIn(ch.Budget, cur))}
+
+	for _, v := range ch.Budgets {
+		m := msgBudgetHistoryFrom
+		if v.Only {
+			m = msgBudgetHistoryOnly
+		}
+
+		lines = append(lines, "• "+l.t(m, v.From, humanizeIn(v.Amount, cur)))
+	}
+
+	return &slack.Msg{Text: strings.Join(lines, "\n")}, nil
+}
+
+func (p *commandProcessor) processRate(
+	ctx context.Context, c slack.SlashCommand, l lang, ch *channel, args []string,
+) (*slack.Msg, error) {
+	if len(args) != 2 {
+		return &slack.Msg{Text: l.t(msgUsage)}, nil
+	}
+
+	if !ch.allows(c.UserID, ch.CanSetBudget) {
+		return notAllowed(l, ch.CanSetBudget), nil
+	}
+
+	cur := strings.ToUpper(args[0])
+	if !knownCurrency(cur) {
+		return &slack.Msg{Text: l.t(msgInvalidCurrency)}, nil
+	}
+
+	rate, err := strconv.ParseFloat(args[1], 64)
+	if err != nil || rate <= 0 {
+		return &slack.Msg{Text: l.t(msgRateNotPositive)}, nil
+	}
+
+	if ch == nil {
+		return &slack.Msg{Text: l.t(msgSetBudgetFirst)}, nil
+	}
+
+	if ch.Rates == nil {
+		ch.Rates = map[string]float64{}
+	}
+
+	ch.Rates[cur] = rate
+
+	if err := p.channelRepo.save(ctx, ch); err != nil {
+		return nil, wrap(http.StatusInternalServerError, "p.channelRepo.save: %w", err)
+	}
+
+	return &slack.Msg{Text: l.t(msgRateSet, c.ChannelName, cur, rate, ch.currency())}, nil
+}
+
+// processLang sets the language of the channel, or of the user with `me`.
+func (p *commandProcessor) processLang(
+	ctx context.Context, c slack.SlashCommand, l lang, ch *channel, args []string,
+) (*slack.Msg, error) {
+	if len(args) != 1 && (len(args) != 2 || args[1] != "me") {
+		return &slack.Msg{Text: l.t(msgUsage)}, nil
+	}
+
+	nl, ok := parseLang(args[0])
+	if !ok {
+		return &slack.Msg{Text: l.t(msgInvalidLang)}, nil
+	}
+
+	if len(args) == 2 {
+		if err := p.userRepo.save(ctx, &user{ID: c.UserID, Lang: string(nl)}); err != nil {
+			return nil, wrap(http.StatusInternalServerError, "p.userRepo.save: %w", err)
+		}
+
+		return &slack.Msg{Text: nl.t(msgUserLangSet, nl)}, nil
+	}
+
+	// Saving a new channel would make it a budget channel.
+	if ch == nil {
+		return &slack.Msg{Text: l.t(msgSetBudgetFirst)}, nil
+	}
+
+	ch.Lang = string(nl)
+
+	if err := p.channelRepo.save(ctx, ch); err != nil {
+		return nil, wrap(http.StatusInternalServerError, "p.channelRepo.save: %w", err)
+	}
+
+	return &slack.Msg{Text: nl.t(msgChannelLangSet, c.ChannelName, nl)}, nil
+}
+
+// processRollover sets what is carried over from the previous month with an optional cap.
+func (p *commandProcessor) processRollover(
+	ctx context.Context, c slack.SlashCommand, l lang, ch *channel, args []string,
+) (*slack.Msg, error) {
+	if len(args) != 1 && len(args) != 2 {
+		return &slack.Msg{Text: l.t(msgUsage)}, nil
+	}
+
+	mode, ok := parseRolloverMode(args[0])
+	if !ok {
+		return &slack.Msg{Text: l.t(msgInvalidRollover)}, nil
+	}
+
+	var limit int64
+
+	if len(args) == 2 {
+		n, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || n < 0 {
+			return &slack.Msg{Text: l.t(msgInvalidRollover)}, nil
+		}
+
+		limit = n
+	}
+
+	if ch == nil {
+		return &slack.Msg{Text: l.t(msgSetBudgetFirst)}, nil
+	}
+
+	if !ch.allows(c.UserID, ch.CanSetBudget) {
+		return notAllowed(l, ch.CanSetBudget), nil
+	}
+
+	ch.Rollover = mode
+	ch.RolloverCap = limit
+
+	if err := p.channelRepo.save(ctx, ch); err != nil {
+		return nil, wrap(http.StatusInternalServerError, "p.channelRepo.save: %w", err)
+	}
+
+	return &slack.Msg{Text: l.t(msgRolloverSet, c.ChannelName, mode)}, nil
 }
 
-func (p *commandProcessor) setBudget(ctx context.Context, chID string, budget int64) error {
-	ch := &channel{
-		ID:     chID,
-		Budget: budget,
+// setBudget sets the budget and returns the updated channel. The user becomes an owner of new channels.
+func (p *commandProcessor) setBudget(
+	ctx context.Context, chID, userID string, budget int64, cur, month string, only bool, now time.Time,
+) (*channel, error) {
+	ch, err := p.channelRepo.findByID(ctx, chID)
+	if errors.Is(err, errNotFound) {
+		ch = &channel{ID: chID, Owners: []string{userID}}
+	} else if err != nil {
+		return nil, fmt.Errorf("p.channelRepo.findByID: %w", err)
+	}
+
+	ch.setBudget(budget, month, only, now)
+
+	if cur != "" {
+		ch.Currency = cur
 	}
 
 	if err := p.channelRepo.save(ctx, ch); err != nil {
-		return fmt.Errorf("p.channelRepo.save: %w", err)
+		return nil, fmt.Errorf("p.channelRepo.save: %w", err)
+	}
+
+	return ch, nil
+}
+
+// processAdd opens the modal to add an expenditure to the channel. It responds nothing on success.
+func (p *commandProcessor) processAdd(
+	ctx context.Context, c slack.SlashCommand, l lang, ch *channel, args []string,
+) (*slack.Msg, error) {
+	if len(args) != 0 {
+		return &slack.Msg{Text: l.t(msgUsage)}, nil
+	}
+
+	if ch == nil {
+		return &slack.Msg{Text: l.t(msgSetBudgetFirst)}, nil
+	}
+
+	if err := p.interactions.openAddModal(ctx, c.TriggerID, c.UserID, addModalMetadata{Channel: ch.ID}, ""); err != nil {
+		return nil, wrap(http.StatusInternalServerError, "p.interactions.openAddModal: %w", err)
 	}
 
-	return nil
+	return nil, nil
 }
@@ -1,14 +1,74 @@
 package main
 
 import (
+	"log/slog"
+	"net"
+	"time"
+
 	"github.com/kelseyhightower/envconfig"
+	"github.com/nownabe/moneysaver/slack"
 	"golang.org/x/xerrors"
 )
 
+const (
+	transportHTTP   = "http"
+	transportSocket = "socket"
+)
+
 type config struct {
-	ProjectID          string `required:"true" split_words:"true"`
-	SlackBotToken      string `required:"true" split_words:"true"`
-	SlackSigningSecret string `required:"true" split_words:"true"`
+	// Required, but can be set in the config file
+	ProjectID string `split_words:"true"`
+	// Required for the http transport
+	SlackSigningSecret string `split_words:"true"`
+	// Previous secrets accepted while rotating the signing secret
+	SlackPreviousSigningSecrets []string `split_words:"true"`
+	// Allowed clock skew of signed requests
+	SlackSignatureTolerance time.Duration `default:"5m" split_words:"true"`
+	// Max size of request bodies from Slack
+	SlackMaxBodyBytes int64 `default:"1048576" split_words:"true"`
+	// Required for a single workspace
+	SlackBotToken string `split_words:"true"`
+	// Set both instead of the bot token to be installed to multiple workspaces with OAuth
+	SlackClientID     string `split_words:"true"`
+	SlackClientSecret string `split_words:"true"`
+	// Redirect URL registered in the app settings. Optional if only one is registered.
+	SlackRedirectURL string `split_words:"true"`
+	// JSON file of AES keys which encrypt bot tokens of workspaces
+	SecretsKeyFile string `split_words:"true"`
+	// http or socket. Socket Mode needs an app-level token (xapp-) with connections:write.
+	Transport     string `default:"http"`
+	SlackAppToken string `split_words:"true"`
+	// Overrides Slack Web API URL, e.g. for a local stand-in
+	SlackAPIURL  string        `envconfig:"SLACK_API_URL"`
+	SlackTimeout time.Duration `default:"10s" split_words:"true"`
+	// How long Slack API calls can take including retries
+	SlackMaxRetryTime time.Duration `default:"2s" split_words:"true"`
+	// JSON file of exchange rates, e.g. {"JPY": {"USD": 150.2}}
+	RatesFile string `split_words:"true"`
+	// Budgets by channel ID, e.g. ABCXXX:100000,DEFYYY:20000
+	Limits       map[string]int64
+	LimitsDryRun bool `split_words:"true"`
+	// Optional YAML file layered over environment variables
+	ConfigFile string `split_words:"true"`
+	// Host to listen on. Cloud Run sets PORT.
+	ListenAddress   string        `split_words:"true"`
+	Port            string        `default:"8080"`
+	ShutdownTimeout time.Duration `default:"10s" split_words:"true"`
+	LogLevel        slog.Level    `default:"info" split_words:"true"`
+	LogFormat       string        `default:"json" split_words:"true"`
+	// Log request bodies at debug level with user contents redacted
+	LogBody bool `split_words:"true"`
+	// none, stdout or otlp
+	TraceExporter    string  `default:"none" split_words:"true"`
+	TraceSampleRatio float64 `default:"1" split_words:"true"`
+	// Port of the separate metrics listener. Empty disables it.
+	MetricsPort string `default:"9090" split_words:"true"`
+	// Days to keep audit log entries. 0 keeps them forever.
+	AuditRetentionDays int `default:"365" split_words:"true"`
+	// Days to keep deleted expenditures in the trash. 0 keeps them forever.
+	TrashRetentionDays int `default:"30" split_words:"true"`
+
+	settings *settings `ignored:"true"`
 }
 
 func newConfig() (*config, error) {
@@ -16,5 +76,99 @@ func newConfig() (*config, error) {
 	if err := envconfig.Process("", &c); err != nil {
 		return nil, xerrors.Errorf("failed to process config: %w", err)
 	}
+
+	c.settings = &settings{}
+
+	if c.ConfigFile != "" {
+		s, err := loadSettings(c.ConfigFile)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to load config file: %w", err)
+		}
+
+		c.settings = s
+
+		if s.ProjectID != "" {
+			c.ProjectID = s.ProjectID
+		}
+
+		if s.RatesFile != "" {
+			c.RatesFile = s.RatesFile
+		}
+	}
+
+	if c.ProjectID == "" {
+		return nil, xerrors.New("required key PROJECT_ID missing value")
+	}
+
+	if c.SlackBotToken == "" && !c.multiWorkspace() {
+		return nil, xerrors.New("required key SLACK_BOT_TOKEN missing value")
+	}
+
+	// Bot tokens of other workspaces must not be stored in plaintext.
+	if c.multiWorkspace() && c.SecretsKeyFile == "" {
+		return nil, xerrors.New("required key SECRETS_KEY_FILE missing value for multiple workspaces")
+	}
+
+	switch c.Transport {
+	case transportHTTP:
+		if c.SlackSigningSecret == "" {
+			return nil, xerrors.New("required key SLACK_SIGNING_SECRET missing value for http transport")
+		}
+	case transportSocket:
+		if c.SlackAppToken == "" {
+			return nil, xerrors.New("required key SLACK_APP_TOKEN missing value for socket transport")
+		}
+	default:
+		return nil, xerrors.Errorf("unknown transport: %s", c.Transport)
+	}
+
+	if c.AuditRetentionDays < 0 {
+		return nil, xerrors.Errorf("AUDIT_RETENTION_DAYS must not be negative: %d", c.AuditRetentionDays)
+	}
+
+	if c.TrashRetentionDays < 0 {
+		return nil, xerrors.Errorf("TRASH_RETENTION_DAYS must not be negative: %d", c.TrashRetentionDays)
+	}
+
 	return &c, nil
 }
+
+// multiWorkspace reports whether the app is distributed to workspaces which install it with OAuth.
+func (c *config) multiWorkspace() bool {
+	return c.SlackClientID != "" && c.SlackClientSecret != ""
+}
+
+func (c *config) addr() string {
+	return net.JoinHostPort(c.ListenAddress, c.Port)
+}
+
+func (c *config) slackOptions() []slack.Option {
+	opts := []slack.Option{
+		slack.WithObserver(observeSlack),
+		slack.WithTimeout(c.SlackTimeout),
+		slack.WithMaxRetryTime(c.SlackMaxRetryTime),
+	}
+
+	if c.SlackAPIURL != "" {
+		opts = append(opts, slack.WithBaseURL(c.SlackAPIURL))
+	}
+
+	return opts
+}
+
+// verifier returns nil without the signing secret, which is optional for the socket transport.
+func (c *config) verifier() *verifier {
+	if c.SlackSigningSecret == "" {
+		return nil
+	}
+
+	v := newVerifier(append([]string{c.SlackSigningSecret}, c.SlackPreviousSigningSecrets...)...)
+	v.tolerance = c.SlackSignatureTolerance
+	v.maxBodyBytes = c.SlackMaxBodyBytes
+
+	return v
+}
+
+func (c *config) metricsAddr() string {
+	return net.JoinHostPort(c.ListenAddress, c.MetricsPort)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"testing"
+)
+
+func Test_newConfig_limits(t *testing.T) {
+	t.Setenv("PROJECT_ID", "project")
+	t.Setenv("SLACK_BOT_TOKEN", "token")
+	t.Setenv("SLACK_SIGNING_SECRET", "secret")
+	t.Setenv("LIMITS", "ABCXXX:100000,DEFYYY:20000")
+	t.Setenv("LIMITS_DRY_RUN", "true")
+
+	c, err := newConfig()
+	if err != nil {
+		t.Fatalf("newConfig: %v", err)
+	}
+
+	if len(c.Limits) != 2 || c.Limits["ABCXXX"] != 100000 || c.Limits["DEFYYY"] != 20000 {
+		t.Errorf("incorrect limits: %v", c.Limits)
+	}
+
+	if !c.LimitsDryRun {
+		t.Errorf("LimitsDryRun should be true")
+	}
+}
+
+func Test_newConfig_transport(t *testing.T) {
+	cases := map[string]struct {
+		transport     string
+		appToken      string
+		signingSecret string
+		wantErr       bool
+	}{
+		"http":                 {transport: "http", signingSecret: "secret"},
+		"http without secret":  {transport: "http", wantErr: true},
+		"socket":               {transport: "socket", appToken: "xapp-token"},
+		"socket with secret":   {transport: "socket", appToken: "xapp-token", signingSecret: "secret"},
+		"socket without token": {transport: "socket", wantErr: true},
+		"unknown transport":    {transport: "grpc", signingSecret: "secret", wantErr: true},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("PROJECT_ID", "project")
+			t.Setenv("SLACK_BOT_TOKEN", "token")
+			t.Setenv("SLACK_SIGNING_SECRET", c.signingSecret)
+			t.Setenv("TRANSPORT", c.transport)
+			t.Setenv("SLACK_APP_TOKEN", c.appToken)
+
+			_, err := newConfig()
+			if c.wantErr != (err != nil) {
+				t.Errorf("incorrect error: %v", err)
+			}
+		})
+	}
+}
+
+func Test_newConfig_workspaces(t *testing.T) {
+	cases := map[string]struct {
+		botToken     string
+		clientID     string
+		clientSecret string
+		keyFile      string
+		multi        bool
+		wantErr      bool
+	}{
+		"bot token":            {botToken: "token"},
+		"oauth":                {clientID: "id", clientSecret: "secret", keyFile: "keys.json", multi: true},
+		"oauth without keys":   {clientID: "id", clientSecret: "secret", wantErr: true},
+		"client id only":       {clientID: "id", wantErr: true},
+		"no token nor clients": {wantErr: true},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("PROJECT_ID", "project")
+			t.Setenv("SLACK_SIGNING_SECRET", "secret")
+			t.Setenv("SLACK_BOT_TOKEN", c.botToken)
+			t.Setenv("SLACK_CLIENT_ID", c.clientID)
+			t.Setenv("SLACK_CLIENT_SECRET", c.clientSecret)
+			t.Setenv("SECRETS_KEY_FILE", c.keyFile)
+
+			cfg, err := newConfig()
+			if c.wantErr != (err != nil) {
+				t.Fatalf("incorrect error: %v", err)
+			}
+
+			if err == nil && cfg.multiWorkspace() != c.multi {
+				t.Errorf("multiWorkspace should be %v", c.multi)
+			}
+		})
+	}
+}
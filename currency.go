@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const defaultCurrency = "JPY"
+
+var errUnknownRate = errors.New("unknown exchange rate")
+
+var (
+	amountPattern = regexp.MustCompile(
+		`^(?:([A-Za-z]{3})\s*|([$€£¥￥]))?\s*([-+]?[0-9]+(?:\.[0-9]+)?)\s*([A-Za-z]{3})?$`)
+
+	currencySymbols = map[string]string{
+		"$": "USD",
+		"€": "EUR",
+		"£": "GBP",
+		"¥": "JPY",
+		"￥": "JPY",
+	}
+
+	// Active ISO 4217 codes. Other three letters such as `2 pm` are not currencies.
+	currencyCodes = map[string]bool{
+		"AED": true, "AFN": true, "ALL": true, "AMD": true, "ANG": true, "AOA": true, "ARS": true, "AUD": true,
+		"AWG": true, "AZN": true, "BAM": true, "BBD": true, "BDT": true, "BGN": true, "BHD": true, "BIF": true,
+		"BMD": true, "BND": true, "BOB": true, "BRL": true, "BSD": true, "BTN": true, "BWP": true, "BYN": true,
+		"BZD": true, "CAD": true, "CDF": true, "CHF": true, "CLP": true, "CNY": true, "COP": true, "CRC": true,
+		"CUP": true, "CVE": true, "CZK": true, "DJF": true, "DKK": true, "DOP": true, "DZD": true, "EGP": true,
+		"ERN": true, "ETB": true, "EUR": true, "FJD": true, "FKP": true, "GBP": true, "GEL": true, "GHS": true,
+		"GIP": true, "GMD": true, "GNF": true, "GTQ": true, "GYD": true, "HKD": true, "HNL": true, "HTG": true,
+		"HUF": true, "IDR": true, "ILS": true, "INR": true, "IQD": true, "IRR": true, "ISK": true, "JMD": true,
+		"JOD": true, "JPY": true, "KES": true, "KGS": true, "KHR": true, "KMF": true, "KPW": true, "KRW": true,
+		"KWD": true, "KYD": true, "KZT": true, "LAK": true, "LBP": true, "LKR": true, "LRD": true, "LSL": true,
+		"LYD": true, "MAD": true, "MDL": true, "MGA": true, "MKD": true, "MMK": true, "MNT": true, "MOP": true,
+		"MRU": true, "MUR": true, "MVR": true, "MWK": true, "MXN": true, "MYR": true, "MZN": true, "NAD": true,
+		"NGN": true, "NIO": true, "NOK": true, "NPR": true, "NZD": true, "OMR": true, "PAB": true, "PEN": true,
+		"PGK": true, "PHP": true, "PKR": true, "PLN": true, "PYG": true, "QAR": true, "RON": true, "RSD": true,
+		"RUB": true, "RWF": true, "SAR": true, "SBD": true, "SCR": true, "SDG": true, "SEK": true, "SGD": true,
+		"SHP": true, "SLE": true, "SOS": true, "SRD": true, "SSP": true, "STN": true, "SVC": true, "SYP": true,
+		"SZL": true, "THB": true, "TJS": true, "TMT": true, "TND": true, "TOP": true, "TRY": true, "TTD": true,
+		"TWD": true, "TZS": true, "UAH": true, "UGX": true, "USD": true, "UYU": true, "UZS": true, "VES": true,
+		"VND": true, "VUV": true, "WST": true, "XAF": true, "XCD": true, "XOF": true, "XPF": true, "YER": true,
+		"ZAR": true, "ZMW": true, "ZWL": true,
+	}
+
+	// Number of digits after the decimal point.
+	currencyDigits = map[string]int{
+		"JPY": 0,
+		"KRW": 0,
+		"VND": 0,
+	}
+)
+
+// knownCurrency reports whether the code is an ISO 4217 code.
+func knownCurrency(code string) bool {
+	return currencyCodes[code]
+}
+
+// amount is an amount as posted.
+// Plain amounts are integers to keep their precision, and amounts in other currencies are converted later.
+type amount struct {
+	plain    int64
+	original float64
+	currency string
+}
+
+// float returns the number as posted regardless of the currency.
+func (a amount) float() float64 {
+	if a.currency == "" {
+		return float64(a.plain)
+	}
+
+	return a.original
+}
+
+// parseAmount parses texts like `1200`, `-500`, `USD 42.50`, `$42.50` or `42.50 EUR`.
+// The currency is empty when the text has no currency code or symbol.
+func parseAmount(text string) (amount, error) {
+	m := amountPattern.FindStringSubmatch(strings.TrimSpace(text))
+	if m == nil {
+		return amount{}, errNotExpenditureMessage
+	}
+
+	code, symbol, number, suffix := m[1], m[2], m[3], m[4]
+
+	var cur string
+
+	switch {
+	case code != "" && suffix != "":
+		return amount{}, errNotExpenditureMessage
+	case code != "":
+		cur = strings.ToUpper(code)
+	case symbol != "":
+		cur = currencySymbols[symbol]
+	case suffix != "":
+		cur = strings.ToUpper(suffix)
+	}
+
+	if cur != "" && !knownCurrency(cur) {
+		return amount{}, errNotExpenditureMessage
+	}
+
+	// Plain numbers keep the traditional integer-only format.
+	if cur == "" {
+		a, err := strconv.ParseInt(number, 10, 64)
+		if err != nil {
+			return amount{}, errNotExpenditureMessage
+		}
+
+		return amount{plain: a}, nil
+	}
+
+	a, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return amount{}, errNotExpenditureMessage
+	}
+
+	return amount{original: a, currency: cur}, nil
+}
+
+func formatAmount(a float64, cur string) string {
+	digits, ok := currencyDigits[cur]
+	if !ok {
+		digits = 2
+	}
+
+	return cur + " " + strconv.FormatFloat(a, 'f', digits, 64)
+}
+
+// rateTable maps a budget currency to rates of other currencies in it.
+// e.g. {"JPY": {"USD": 150.2}} means 1 USD = 150.2 JPY.
+type rateTable map[string]map[string]float64
+
+func loadRateTable(path string) (rateTable, error) {
+	if path == "" {
+		return rateTable{}, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile: %w", err)
+	}
+
+	var t rateTable
+	if err := json.Unmarshal(b, &t); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	for base, rates := range t {
+		if !knownCurrency(base) {
+			return nil, fmt.Errorf("unknown currency: %s", base)
+		}
+
+		for cur, rate := range rates {
+			if !knownCurrency(cur) {
+				return nil, fmt.Errorf("unknown currency of %s: %s", base, cur)
+			}
+
+			if rate <= 0 {
+				return nil, fmt.Errorf("rate of %s in %s must be positive: %v", cur, base, rate)
+			}
+		}
+	}
+
+	return t, nil
+}
+
+// rate returns the rate of cur in the channel's budget currency.
+// Rates set in the channel take precedence over the table.
+func (t rateTable) rate(ch *channel, cur string) (float64, error) {
+	base := ch.currency()
+	if cur == base {
+		return 1, nil
+	}
+
+	if r, ok := ch.Rates[cur]; ok {
+		return r, nil
+	}
+
+	if r, ok := t[base][cur]; ok {
+		return r, nil
+	}
+
+	return 0, fmt.Errorf("%w: %s/%s", errUnknownRate, cur, base)
+}
+
+// convert sets the amount in the channel's budget currency.
+func (t rateTable) convert(ch *channel, ex *expenditure) error {
+	if ex.Currency == "" {
+		return nil
+	}
+
+	r, err := t.rate(ch, ex.Currency)
+	if err != nil {
+		return err
+	}
+
+	ex.Amount = int64(math.Round(ex.OriginalAmount * r))
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_parseAmount(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		text string
+		a    amount
+		err  error
+	}{
+		"integer":         {text: "1200", a: amount{plain: 1200}},
+		"large integer":   {text: "9007199254740993", a: amount{plain: 9007199254740993}},
+		"overflow":        {text: "9223372036854775808", err: errNotExpenditureMessage},
+		"code prefix":     {text: "USD 42.50", a: amount{original: 42.5, currency: "USD"}},
+		"code suffix":     {text: "42.50 eur", a: amount{original: 42.5, currency: "EUR"}},
+		"symbol":          {text: "$42.50", a: amount{original: 42.5, currency: "USD"}},
+		"yen symbol":      {text: "¥1200", a: amount{original: 1200, currency: "JPY"}},
+		"plain decimal":   {text: "12.5", err: errNotExpenditureMessage},
+		"both codes":      {text: "USD 10 EUR", err: errNotExpenditureMessage},
+		"not number":      {text: "not number", err: errNotExpenditureMessage},
+		"trailing spaces": {text: " 300 ", a: amount{plain: 300}},
+		"refund":          {text: "-500", a: amount{plain: -500}},
+		"signed":          {text: "+500", a: amount{plain: 500}},
+		"refund in code":  {text: "USD -5", a: amount{original: -5, currency: "USD"}},
+		"not currency":    {text: "2 pm", err: errNotExpenditureMessage},
+		"minutes":         {text: "10 min", err: errNotExpenditureMessage},
+		"word prefix":     {text: "car 1480", err: errNotExpenditureMessage},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			a, err := parseAmount(c.text)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("expected error %v, but %v", c.err, err)
+			}
+
+			if a != c.a {
+				t.Errorf("expected %+v, but %+v", c.a, a)
+			}
+		})
+	}
+}
+
+func Test_rateTable_convert(t *testing.T) {
+	t.Parallel()
+
+	table := rateTable{"JPY": {"USD": 150, "EUR": 160}}
+	ch := &channel{Rates: map[string]float64{"USD": 150.2}}
+
+	cases := map[string]struct {
+		ex     *expenditure
+		amount int64
+		err    error
+	}{
+		"no currency":   {ex: &expenditure{Amount: 1200}, amount: 1200},
+		"same currency": {ex: &expenditure{OriginalAmount: 1200, Currency: "JPY"}, amount: 1200},
+		"channel rate":  {ex: &expenditure{OriginalAmount: 40, Currency: "USD"}, amount: 6008},
+		"table rate":    {ex: &expenditure{OriginalAmount: 10, Currency: "EUR"}, amount: 1600},
+		"unknown":       {ex: &expenditure{OriginalAmount: 10, Currency: "GBP"}, err: errUnknownRate},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := table.convert(ch, c.ex)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("expected error %v, but %v", c.err, err)
+			}
+
+			if c.ex.Amount != c.amount {
+				t.Errorf("expected %d, but %d", c.amount, c.ex.Amount)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errFutureDate = errors.New("future date")
+
+var (
+	monthDayPattern = regexp.MustCompile(`^([0-9]{1,2})/([0-9]{1,2})$`)
+	fullDatePattern = regexp.MustCompile(`^([0-9]{4})[-/]([0-9]{1,2})[-/]([0-9]{1,2})$`)
+
+	// Days relative to the posted date
+	relativeDates = map[string]int{
+		"today":     0,
+		"yesterday": -1,
+		"今日":        0,
+		"昨日":        -1,
+		"一昨日":       -2,
+		"おととい":      -2,
+	}
+)
+
+// parseDatePrefix splits texts like `9/30 1200`, `2026-09-30 1200` or `yesterday 800` into the date and the rest.
+// Dates are relative to now, dates without year are the latest ones not after now, and full dates after now are invalid.
+// It returns false when the text has no valid date prefix.
+func parseDatePrefix(text string, now time.Time) (time.Time, string, bool) {
+	prefix, rest, ok := strings.Cut(strings.TrimSpace(text), " ")
+	if !ok {
+		return time.Time{}, "", false
+	}
+
+	y, m, d := now.Date()
+	loc := now.Location()
+
+	if days, ok := relativeDates[strings.ToLower(prefix)]; ok {
+		return time.Date(y, m, d+days, 0, 0, 0, 0, loc), rest, true
+	}
+
+	if md := monthDayPattern.FindStringSubmatch(prefix); md != nil {
+		month, _ := strconv.Atoi(md[1])
+		day, _ := strconv.Atoi(md[2])
+
+		date, ok := validDate(y, month, day, loc)
+		if !ok {
+			return time.Time{}, "", false
+		}
+
+		if date.After(now) {
+			if date, ok = validDate(y-1, month, day, loc); !ok {
+				return time.Time{}, "", false
+			}
+		}
+
+		return date, rest, true
+	}
+
+	if ymd := fullDatePattern.FindStringSubmatch(prefix); ymd != nil {
+		year, _ := strconv.Atoi(ymd[1])
+		month, _ := strconv.Atoi(ymd[2])
+		day, _ := strconv.Atoi(ymd[3])
+
+		// Future dates are likely typos like 2062-09-30.
+		date, ok := validDate(year, month, day, loc)
+		if !ok || date.After(now) {
+			return time.Time{}, "", false
+		}
+
+		return date, rest, true
+	}
+
+	return time.Time{}, "", false
+}
+
+// validDate returns the date unless it overflows like 2/30.
+func validDate(year, month, day int, loc *time.Location) (time.Time, bool) {
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, loc)
+	if date.Year() != year || int(date.Month()) != month || date.Day() != day {
+		return time.Time{}, false
+	}
+
+	return date, true
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack/slackevents"
+)
+
+func Test_parseDatePrefix(t *testing.T) {
+	t.Parallel()
+
+	jst := time.FixedZone("JST", 9*60*60)
+	now := time.Date(2026, 10, 1, 0, 30, 0, 0, jst)
+
+	cases := map[string]struct {
+		text string
+		date string
+		rest string
+		ok   bool
+	}{
+		"month and day":       {"9/30 1200", "2026-09-30", "1200", true},
+		"month and day ahead": {"12/25 5000", "2025-12-25", "5000", true},
+		"full date":           {"2026-09-15 USD 12.5", "2026-09-15", "USD 12.5", true},
+		"full date slash":     {"2026/9/15 300", "2026-09-15", "300", true},
+		"full date today":     {"2026-10-01 300", "2026-10-01", "300", true},
+		"full date future":    {"2062-09-30 300", "", "", false},
+		"full date tomorrow":  {"2026-10-02 300", "", "", false},
+		"yesterday":           {"yesterday 800", "2026-09-30", "800", true},
+		"capitalized":         {"Yesterday 800", "2026-09-30", "800", true},
+		"japanese":            {"昨日 800", "2026-09-30", "800", true},
+		"today":               {"today 800", "2026-10-01", "800", true},
+		"invalid date":        {"2/30 800", "", "", false},
+		"no prefix":           {"1200", "", "", false},
+		"not date":            {"lunch 1200", "", "", false},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			date, rest, ok := parseDatePrefix(c.text, now)
+			if ok != c.ok {
+				t.Fatalf("parseDatePrefix(%q) should be %v", c.text, c.ok)
+			}
+
+			if !ok {
+				return
+			}
+
+			if date.Format(dateLayout) != c.date || rest != c.rest {
+				t.Errorf("parseDatePrefix(%q) should be %s, %q but %s, %q", c.text, c.date, c.rest, date.Format(dateLayout), rest)
+			}
+
+			if date.Location() != jst {
+				t.Errorf("date should be in the location of now: %v", date.Location())
+			}
+		})
+	}
+}
+
+func Test_newExpenditure_backdated(t *testing.T) {
+	t.Parallel()
+
+	jst := time.FixedZone("JST", 9*60*60)
+
+	// 2026-10-01 00:30 JST, which is still September in UTC.
+	ev := &slackevents.MessageEvent{Channel: "ch1", Text: "yesterday 800", TimeStamp: "1790782200.000100"}
+
+	ex, err := newExpenditure(ev, jst)
+	if err != nil {
+		t.Fatalf("newExpenditure: %v", err)
+	}
+
+	if ex.Amount != 800 || ex.key() != "2026-09/1790782200.000100" || !ex.backdated() {
+		t.Errorf("expenditure should be backdated to September: %+v, %s", ex, ex.key())
+	}
+
+	ev.Text = "800"
+
+	ex, err = newExpenditure(ev, jst)
+	if err != nil {
+		t.Fatalf("newExpenditure: %v", err)
+	}
+
+	if ex.key() != "2026-10/1790782200.000100" || ex.backdated() {
+		t.Errorf("expenditure should be in October of the channel's timezone: %s", ex.key())
+	}
+}
@@ -29,6 +29,10 @@ func (e httpError) Error() string {
 	return e.err.Error()
 }
 
+func (e httpError) Unwrap() error {
+	return e.err
+}
+
 func (e httpError) Status() int {
 	return e.status
 }
@@ -4,12 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/nownabe/moneysaver/slack"
 	"github.com/slack-go/slack/slackevents"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 func ts2time(ts string) (time.Time, error) {
@@ -21,28 +24,129 @@ func ts2time(ts string) (time.Time, error) {
 	return time.Unix(int64(t), 0), nil
 }
 
+// expenditureOp is an operation on an expenditure which is replied.
+type expenditureOp string
+
+const (
+	opAdded    expenditureOp = "added"
+	opDeleted  expenditureOp = "deleted"
+	opUpdated  expenditureOp = "updated"
+	opRestored expenditureOp = "restored"
+)
+
 type eventProcessor struct {
 	slack           slack.Client
 	channelRepo     *channelRepo
 	expenditureRepo *expenditureRepo
+	userRepo        *userRepo
+	rates           rateTable
+	settings        *settingsStore
+	// Nil for a single workspace
+	teams *teamClients
+
+	// Processing after acknowledgement, which is canceled when closing is closed
+	background sync.WaitGroup
+	closing    chan struct{}
+	closeOnce  sync.Once
 }
 
 // process returns response body and error.
-func (p *eventProcessor) process(ctx context.Context, ev slackevents.EventsAPIEvent) error {
+func (p *eventProcessor) process(ctx context.Context, ev slackevents.EventsAPIEvent) (err error) {
 	if ev.Type != slackevents.CallbackEvent {
 		return nil
 	}
 
+	var subtype, actor, eventID string
+	if mev, ok := ev.InnerEvent.Data.(*slackevents.MessageEvent); ok {
+		subtype = mev.SubType
+		actor = messageActor(mev)
+	}
+
+	if cb, ok := ev.Data.(*slackevents.EventsAPICallbackEvent); ok {
+		eventID = cb.EventID
+	}
+
+	ctx = withActor(ctx, actor, eventID)
+
+	defer observeEvent(ev.InnerEvent.Type, subtype, time.Now(), &err)
+
 	switch ev := ev.InnerEvent.Data.(type) {
 	case *slackevents.MessageEvent:
 		if err := p.processMessageEvent(ctx, ev); err != nil {
 			return wrap(http.StatusInternalServerError, "p.processMessageEvent: %w", err)
 		}
+	case *slackevents.AppHomeOpenedEvent:
+		// Reading all channels of the user may take longer than Slack waits for the acknowledgement.
+		p.goBackground(ctx, "p.processAppHomeOpened", func(ctx context.Context) error {
+			return p.processAppHomeOpened(ctx, ev)
+		})
+	case *slackevents.AppUninstalledEvent:
+		if p.teams == nil {
+			return nil
+		}
+
+		if err := p.teams.uninstall(ctx); err != nil {
+			return wrap(http.StatusInternalServerError, "p.teams.uninstall: %w", err)
+		}
 	}
 
 	return nil
 }
 
+// goBackground runs f after the event is acknowledged, and logs its error.
+// f is canceled on Close.
+func (p *eventProcessor) goBackground(ctx context.Context, name string, f func(context.Context) error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), backgroundTimeout)
+
+	p.background.Add(1)
+
+	go func() {
+		select {
+		case <-p.closing:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	go func() {
+		defer p.background.Done()
+		defer cancel()
+
+		if err := f(ctx); err != nil {
+			logger.ErrorContext(ctx, name, slog.Any("err", err))
+		}
+	}()
+}
+
+// Close cancels processing after acknowledgement and waits for it to return,
+// so that shutdown isn't blocked until backgroundTimeout.
+func (p *eventProcessor) Close() error {
+	p.closeOnce.Do(func() {
+		if p.closing != nil {
+			close(p.closing)
+		}
+	})
+
+	p.background.Wait()
+
+	return nil
+}
+
+// messageActor returns who posted or edited the message.
+// Deletions don't tell who deleted it, which may be an admin rather than the author.
+func messageActor(ev *slackevents.MessageEvent) string {
+	switch {
+	case ev.SubType == "message_deleted":
+		return actorUnknown
+	case ev.User != "":
+		return ev.User
+	case ev.Message != nil:
+		return ev.Message.User
+	}
+
+	return ""
+}
+
 func (p *eventProcessor) processMessageEvent(ctx context.Context, ev *slackevents.MessageEvent) error {
 	switch ev.SubType {
 	case "":
@@ -58,7 +162,10 @@ func (p *eventProcessor) processMessageEvent(ctx context.Context, ev *slackevent
 	return nil
 }
 
-func (p *eventProcessor) processExpenditure(ctx context.Context, ev *slackevents.MessageEvent) error {
+func (p *eventProcessor) processExpenditure(ctx context.Context, ev *slackevents.MessageEvent) (err error) {
+	ctx, end := startSpan(ctx, "processExpenditure", attribute.String("slack.channel", ev.Channel))
+	defer end(&err)
+
 	ch, err := p.channelRepo.findByID(ctx, ev.Channel)
 	if err != nil {
 		if errors.Is(err, errNotFound) {
@@ -68,45 +175,100 @@ func (p *eventProcessor) processExpenditure(ctx context.Context, ev *slackevents
 		return fmt.Errorf("p.channelRepo.findByID: %w", err)
 	}
 
-	ex, err := newExpenditure(ev)
+	cs := p.settings.get().channel(ch.ID)
+	cs.applyTo(ch)
+
+	ex, err := newExpenditure(ev, cs.location())
 	if errors.Is(err, errNotExpenditureMessage) {
 		return nil
 	} else if err != nil {
 		return fmt.Errorf("newExpenditure: %w", err)
 	}
 
+	// Unknown rates are user errors, so reply it and don't let Slack retry.
+	if err := p.rates.convert(ch, ex); err != nil {
+		logger.InfoContext(ctx, "unknown rate", slog.Any("err", err))
+
+		if err := p.replyError(ctx, ch, ev.User, msgUnknownRate, ex.Currency); err != nil {
+			logger.ErrorContext(ctx, "failed to reply error", slog.Any("err", err))
+		}
+
+		return nil
+	}
+
+	if err := p.recordExpenditure(ctx, ch, cs, ev.User, ex); err != nil {
+		return fmt.Errorf("p.recordExpenditure: %w", err)
+	}
+
+	return nil
+}
+
+// recordExpenditure adds the expenditure posted or entered by the user and replies the total.
+func (p *eventProcessor) recordExpenditure(
+	ctx context.Context, ch *channel, cs channelSettings, userID string, ex *expenditure,
+) error {
 	if err := p.expenditureRepo.add(ctx, ex); err != nil {
 		err := fmt.Errorf("p.expenditureRepo.add: %w", err)
 
-		if err := p.replyError(ctx, ev.Channel, err); err != nil {
-			logger.Printf("failed to reply error: %v", err)
+		if err := p.replyError(ctx, ch, userID, msgProcessFailed); err != nil {
+			logger.ErrorContext(ctx, "failed to reply error", slog.Any("err", err))
 		}
 
 		return err
 	}
 
-	total, err := p.expenditureRepo.total(ctx, ex)
-	if err != nil {
-		err := fmt.Errorf("p.store.total: %w", err)
+	expendituresTotal.WithLabelValues(string(opAdded)).Inc()
 
-		if err := p.replyError(ctx, ev.Channel, err); err != nil {
-			logger.Printf("failed to reply error: %v", err)
+	p.replyLater(ctx, ch, cs, userID, ex, opAdded)
+
+	return nil
+}
+
+// replyLater replies the operation with the monthly total after acknowledgement,
+// since the rollover and period budgets read past months and may miss the deadline of Slack.
+// Failures are replied as errors.
+func (p *eventProcessor) replyLater(
+	ctx context.Context, ch *channel, cs channelSettings, userID string, ex *expenditure, op expenditureOp,
+) {
+	p.goBackground(ctx, "p.replyTotal", func(ctx context.Context) error {
+		if err := p.replyTotal(ctx, ch, cs, userID, ex, op); err != nil {
+			if err := p.replyError(ctx, ch, userID, msgProcessFailed); err != nil {
+				logger.ErrorContext(ctx, "failed to reply error", slog.Any("err", err))
+			}
+
+			return err
 		}
 
-		return err
+		return nil
+	})
+}
+
+// replyTotal replies the operation with the monthly total.
+func (p *eventProcessor) replyTotal(
+	ctx context.Context, ch *channel, cs channelSettings, userID string, ex *expenditure, op expenditureOp,
+) error {
+	total, err := p.expenditureRepo.total(ctx, ex)
+	if err != nil {
+		return fmt.Errorf("p.expenditureRepo.total: %w", err)
 	}
 
-	if err := p.replySuccess(ctx, ev.Channel, ch.Budget, total, ex, false); err != nil {
+	if err := p.replySuccess(ctx, ch, cs, userID, total, ex, op); err != nil {
 		return fmt.Errorf("p.replySuccess: %w", err)
 	}
 
 	return nil
 }
 
-func (p *eventProcessor) replyError(ctx context.Context, channel string, err error) error {
+// replyError replies the failure in the language of the user.
+// Callers log details of errors instead of posting them to the channel.
+func (p *eventProcessor) replyError(
+	ctx context.Context, ch *channel, userID string, m message, args ...interface{},
+) error {
+	l := langFor(ctx, p.userRepo, ch, userID)
+
 	r := &slack.ChatPostMessageReq{
-		Channel:   channel,
-		Text:      "```\n" + err.Error() + "\n```",
+		Channel:   ch.ID,
+		Text:      l.t(m, args...),
 		Username:  "MoneySaver",
 		IconEmoji: ":money_with_wings:",
 	}
@@ -118,19 +280,67 @@ func (p *eventProcessor) replyError(ctx context.Context, channel string, err err
 	return nil
 }
 
-func (p *eventProcessor) replySuccess(ctx context.Context, channel string, limit, total int64, ex *expenditure, deleted bool) error {
+// replySuccess replies in the language of the user who posted the expenditure.
+// Replies to added, updated or restored expenditures have buttons to undo, edit and categorize them,
+// and replies to deleted ones have a button to restore them.
+func (p *eventProcessor) replySuccess(
+	ctx context.Context, ch *channel, cs channelSettings, userID string, total int64, ex *expenditure, op expenditureOp,
+) error {
+	if cs.reply() == replyNone {
+		return nil
+	}
+
 	var text, usage string
 
-	if deleted {
-		text = "🗑 カード利用を削除しました。"
-		usage = "削除額"
-	} else {
-		text = "💸 カード利用を登録しました。"
-		usage = "利用額"
+	l := langFor(ctx, p.userRepo, ch, userID)
+
+	cur := ch.currency()
+	month := ex.effective().Format(monthLayout)
+	budget := ch.budgetFor(month)
+
+	totals := newMonthTotals(p.expenditureRepo, ch.ID)
+
+	carry, err := carryOver(ctx, totals, ch, month)
+	if err != nil {
+		return fmt.Errorf("carryOver: %w", err)
+	}
+
+	limit := budget + carry
+
+	amount := humanizeIn(ex.Amount, cur)
+	if ex.foreign(cur) {
+		amount += " (" + formatAmount(ex.OriginalAmount, ex.Currency) + ")"
+	}
+
+	switch op {
+	case opDeleted:
+		text = l.t(msgExpenditureDeleted)
+		usage = l.t(msgFieldDeleted)
+	case opUpdated:
+		text = l.t(msgExpenditureUpdated)
+		usage = l.t(msgFieldUsed)
+	case opRestored:
+		text = l.t(msgExpenditureRestored)
+		usage = l.t(msgFieldUsed)
+	default:
+		text = l.t(msgExpenditureAdded)
+		usage = l.t(msgFieldUsed)
+
+		if ex.Recurring != "" {
+			text += "\n" + l.t(msgRecurringRecorded, ex.Memo)
+		}
+
+		if ex.backdated() {
+			text += "\n" + l.t(msgBackdated, ex.effective().Format(dateLayout))
+		}
+
+		if t := cs.crossedThreshold(limit, total, ex.Amount); t > 0 {
+			text += "\n" + l.t(msgThresholdCrossed, t)
+		}
 	}
 
 	r := &slack.ChatPostMessageReq{
-		Channel:   channel,
+		Channel:   ch.ID,
 		Text:      text,
 		Username:  "MoneySaver",
 		IconEmoji: ":money_with_wings:",
@@ -139,22 +349,22 @@ func (p *eventProcessor) replySuccess(ctx context.Context, channel string, limit
 				Fields: []*slack.AttachmentField{
 					{
 						Title: usage,
-						Value: humanize(ex.Amount),
+						Value: amount,
 						Short: true,
 					},
 					{
-						Title: "今月の利用可能残額",
-						Value: humanize(limit - total),
+						Title: l.t(msgFieldRemaining),
+						Value: humanizeIn(limit-total, cur),
 						Short: true,
 					},
 					{
-						Title: "今月の合計利用額",
-						Value: humanize(total),
+						Title: l.t(msgFieldTotal),
+						Value: humanizeIn(total, cur),
 						Short: true,
 					},
 					{
-						Title: "今月の設定上限額",
-						Value: humanize(limit),
+						Title: l.t(msgFieldBudget),
+						Value: humanizeIn(budget, cur),
 						Short: true,
 					},
 				},
@@ -162,6 +372,43 @@ func (p *eventProcessor) replySuccess(ctx context.Context, channel string, limit
 		},
 	}
 
+	if ch.rollover() != rolloverNone {
+		r.Attachments[0].Fields = append(r.Attachments[0].Fields, &slack.AttachmentField{
+			Title: l.t(msgFieldRollover),
+			Value: humanizeIn(carry, cur),
+			Short: true,
+		})
+	}
+
+	positions, err := budgetPositions(ctx, totals, l, ch, month)
+	if err != nil {
+		return fmt.Errorf("budgetPositions: %w", err)
+	}
+
+	for _, pos := range positions {
+		r.Attachments[0].Fields = append(r.Attachments[0].Fields, &slack.AttachmentField{
+			Title: pos.label,
+			Value: formatPosition(pos, cur),
+			Short: true,
+		})
+	}
+
+	actions := expenditureActions(l, cs, ex)
+	if op == opDeleted {
+		actions = restoreActions(l, ex)
+	}
+
+	r.Blocks = []*slack.Block{
+		{Type: slack.BlockSection, Text: slack.Markdown(text)},
+		actions,
+	}
+
+	// Expenditures entered with the modal have no message to thread.
+	// Restored ones are replied in the channel since their messages may be deleted.
+	if cs.reply() == replyThread && !ex.Manual && op != opRestored {
+		r.ThreadTS = ex.TS
+	}
+
 	if err := p.slack.ChatPostMessage(ctx, r); err != nil {
 		return fmt.Errorf("p.slack.ChatPostMessage: %w", err)
 	}
@@ -169,7 +416,10 @@ func (p *eventProcessor) replySuccess(ctx context.Context, channel string, limit
 	return nil
 }
 
-func (p *eventProcessor) processMessageDeletedEvent(ctx context.Context, ev *slackevents.MessageEvent) error {
+func (p *eventProcessor) processMessageDeletedEvent(ctx context.Context, ev *slackevents.MessageEvent) (err error) {
+	ctx, end := startSpan(ctx, "processMessageDeletedEvent", attribute.String("slack.channel", ev.Channel))
+	defer end(&err)
+
 	ch, err := p.channelRepo.findByID(ctx, ev.Channel)
 	if errors.Is(err, errNotFound) {
 		return nil
@@ -177,31 +427,36 @@ func (p *eventProcessor) processMessageDeletedEvent(ctx context.Context, ev *sla
 		return fmt.Errorf("p.channelRepo.findByID: %w", err)
 	}
 
-	ex, err := newExpenditureFromPreviousMessage(ev)
+	cs := p.settings.get().channel(ch.ID)
+	cs.applyTo(ch)
+
+	posted, err := newExpenditureFromPreviousMessage(ev, cs.location())
 	if errors.Is(err, errNotExpenditureMessage) {
 		return nil
 	} else if err != nil {
 		return fmt.Errorf("newExpenditure: %w", err)
 	}
 
-	if err := p.expenditureRepo.delete(ctx, ex); err != nil {
-		return fmt.Errorf("p.expenditureRepo.delete: %w", err)
+	// Use the stored amount, which was converted with rates when it was posted.
+	ex, err := p.expenditureRepo.findByKey(ctx, ch.ID, posted.key())
+	if errors.Is(err, errNotFound) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("p.expenditureRepo.findByKey: %w", err)
 	}
 
-	total, err := p.expenditureRepo.total(ctx, ex)
-	if err != nil {
-		err := fmt.Errorf("p.store.total: %w", err)
+	ex.in(cs.location())
 
-		if err := p.replyError(ctx, ev.Channel, err); err != nil {
-			logger.Printf("failed to reply error: %v", err)
-		}
-
-		return err
+	// It may have been deleted with Undo in the meantime.
+	if err := p.expenditureRepo.delete(ctx, ex); errors.Is(err, errNotFound) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("p.expenditureRepo.delete: %w", err)
 	}
 
-	if err := p.replySuccess(ctx, ev.Channel, ch.Budget, total, ex, true); err != nil {
-		return fmt.Errorf("p.replySuccess: %w", err)
-	}
+	expendituresTotal.WithLabelValues(string(opDeleted)).Inc()
+
+	p.replyLater(ctx, ch, cs, ev.PreviousMessage.User, ex, opDeleted)
 
 	return nil
 }
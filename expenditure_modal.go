@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nownabe/moneysaver/slack"
+	slackgo "github.com/slack-go/slack"
+)
+
+// Callback IDs of shortcuts and the modal to add expenditures.
+const (
+	shortcutAdd            = "expenditure.add"
+	shortcutAddFromMessage = "expenditure.addFromMessage"
+
+	viewAdd = "expenditure.add"
+
+	blockChannel  = "channel"
+	blockCategory = "category"
+	blockMemo     = "memo"
+	blockDate     = "date"
+	blockPayer    = "payer"
+
+	dateLayout = "2006-01-02"
+)
+
+// addModalMetadata is the private metadata of the modal.
+// Channel is empty when the modal is opened from the global shortcut, so it has a channel input.
+type addModalMetadata struct {
+	Channel string `json:"channel,omitempty"`
+	// Slack timestamp of the message which the modal is opened from
+	TS string `json:"ts,omitempty"`
+}
+
+// processAddShortcut opens the modal from the global shortcut or the message shortcut.
+// The message shortcut prefills the amount and records the expenditure for the message.
+func (p *interactionProcessor) processAddShortcut(ctx context.Context, cb *slackgo.InteractionCallback) error {
+	var (
+		md     addModalMetadata
+		amount string
+	)
+
+	if cb.Type == slackgo.InteractionTypeMessageAction {
+		md = addModalMetadata{Channel: cb.Channel.ID, TS: cb.Message.Timestamp}
+
+		if _, err := parseAmount(cb.Message.Text); err == nil {
+			amount = strings.TrimSpace(cb.Message.Text)
+		}
+	}
+
+	return p.openAddModal(ctx, cb.TriggerID, cb.User.ID, md, amount)
+}
+
+// openAddModal opens the modal to add an expenditure.
+func (p *interactionProcessor) openAddModal(
+	ctx context.Context, triggerID, userID string, md addModalMetadata, amount string,
+) error {
+	var (
+		ch *channel
+		cs = p.settings.get().Defaults
+	)
+
+	if md.Channel != "" {
+		c, s, err := p.findChannel(ctx, md.Channel)
+		if err == nil {
+			ch, cs = c, s
+		} else if !errors.Is(err, errNotFound) {
+			return err
+		}
+	}
+
+	l := langFor(ctx, p.userRepo, ch, userID)
+
+	b, err := json.Marshal(md)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	r := &slack.ViewsOpenReq{
+		TriggerID: triggerID,
+		View: &slack.View{
+			Type:            slack.ViewModal,
+			CallbackID:      viewAdd,
+			PrivateMetadata: string(b),
+			Title:           slack.PlainText(l.t(msgAddTitle)),
+			Submit:          slack.PlainText(l.t(msgAdd)),
+			Close:           slack.PlainText(l.t(msgCancel)),
+			Blocks:          addModalBlocks(l, cs, md, userID, amount),
+		},
+	}
+
+	if err := p.slack.ViewsOpen(ctx, r); err != nil {
+		return fmt.Errorf("p.slack.ViewsOpen: %w", err)
+	}
+
+	return nil
+}
+
+func addModalBlocks(l lang, cs channelSettings, md addModalMetadata, userID, amount string) []*slack.Block {
+	var blocks []*slack.Block
+
+	if md.Channel == "" {
+		blocks = append(blocks, &slack.Block{
+			Type:    slack.BlockInput,
+			BlockID: blockChannel,
+			Label:   slack.PlainText(l.t(msgFieldChannel)),
+			Element: &slack.Element{
+				Type:                         slack.ElementConversationsSelect,
+				ActionID:                     blockChannel,
+				DefaultToCurrentConversation: true,
+			},
+		})
+	}
+
+	categories := &slack.Element{
+		Type:     slack.ElementStaticSelect,
+		ActionID: blockCategory,
+	}
+
+	for _, c := range cs.categories() {
+		categories.Options = append(categories.Options, &slack.OptionObject{Text: slack.PlainText(c), Value: c})
+	}
+
+	return append(blocks,
+		&slack.Block{
+			Type:    slack.BlockInput,
+			BlockID: blockAmount,
+			Label:   slack.PlainText(l.t(msgFieldAmount)),
+			Element: &slack.Element{
+				Type:         slack.ElementPlainTextInput,
+				ActionID:     blockAmount,
+				InitialValue: amount,
+			},
+		},
+		&slack.Block{
+			Type:     slack.BlockInput,
+			BlockID:  blockCategory,
+			Label:    slack.PlainText(l.t(msgFieldCategory)),
+			Element:  categories,
+			Optional: true,
+		},
+		&slack.Block{
+			Type:     slack.BlockInput,
+			BlockID:  blockMemo,
+			Label:    slack.PlainText(l.t(msgFieldMemo)),
+			Element:  &slack.Element{Type: slack.ElementPlainTextInput, ActionID: blockMemo},
+			Optional: true,
+		},
+		&slack.Block{
+			Type:    slack.BlockInput,
+			BlockID: blockDate,
+			Label:   slack.PlainText(l.t(msgFieldDate)),
+			Element: &slack.Element{
+				Type:        slack.ElementDatePicker,
+				ActionID:    blockDate,
+				InitialDate: time.Now().In(cs.location()).Format(dateLayout),
+			},
+		},
+		&slack.Block{
+			Type:    slack.BlockInput,
+			BlockID: blockPayer,
+			Label:   slack.PlainText(l.t(msgFieldPayer)),
+			Element: &slack.Element{
+				Type:        slack.ElementUsersSelect,
+				ActionID:    blockPayer,
+				InitialUser: userID,
+			},
+		},
+	)
+}
+
+// submitAdd records the expenditure through the same path as posted ones.
+func (p *interactionProcessor) submitAdd(
+	ctx context.Context, cb *slackgo.InteractionCallback,
+) (*slackgo.ViewSubmissionResponse, error) {
+	var md addModalMetadata
+	if err := json.Unmarshal([]byte(cb.View.PrivateMetadata), &md); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	chID, errBlock := md.Channel, blockAmount
+	if chID == "" {
+		chID, errBlock = viewValue(cb, blockChannel).SelectedConversation, blockChannel
+	}
+
+	ch, cs, err := p.findChannel(ctx, chID)
+	if errors.Is(err, errNotFound) {
+		l := langFor(ctx, p.userRepo, nil, cb.User.ID)
+
+		return slackgo.NewErrorsViewSubmissionResponse(map[string]string{errBlock: l.t(msgNoBudget)}), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	l := langFor(ctx, p.userRepo, ch, cb.User.ID)
+
+	ex, err := newExpenditureFromView(cb, ch, cs, md, time.Now())
+	if errors.Is(err, errFutureDate) {
+		return slackgo.NewErrorsViewSubmissionResponse(map[string]string{blockDate: l.t(msgFutureDate)}), nil
+	} else if err != nil {
+		return slackgo.NewErrorsViewSubmissionResponse(map[string]string{blockAmount: l.t(msgInvalidAmount)}), nil
+	}
+
+	if err := p.rates.convert(ch, ex); err != nil {
+		return slackgo.NewErrorsViewSubmissionResponse(map[string]string{blockAmount: l.t(msgUnknownRate, ex.Currency)}), nil
+	}
+
+	// Don't overwrite the expenditure of the message, which may be others'.
+	if !ex.Manual {
+		recorded, err := p.recorded(ctx, ex)
+		if err != nil {
+			return nil, err
+		}
+
+		if recorded {
+			return slackgo.NewErrorsViewSubmissionResponse(map[string]string{blockAmount: l.t(msgAlreadyRecorded)}), nil
+		}
+	}
+
+	if err := p.recordExpenditure(ctx, ch, cs, cb.User.ID, ex); err != nil {
+		return nil, fmt.Errorf("p.recordExpenditure: %w", err)
+	}
+
+	return nil, nil
+}
+
+// recorded reports whether the message of the expenditure has been recorded in the month of the message
+// or in the month of the expenditure.
+func (p *interactionProcessor) recorded(ctx context.Context, ex *expenditure) (bool, error) {
+	keys := []string{ex.key()}
+
+	if t, err := ts2time(ex.TS); err == nil {
+		keys = append(keys, t.In(ex.Timestamp.Location()).Format(monthLayout)+"/"+ex.TS)
+	}
+
+	for _, key := range keys {
+		ok, err := p.expenditureRepo.exists(ctx, ex.Channel, key)
+		if err != nil {
+			return false, fmt.Errorf("p.expenditureRepo.exists: %w", err)
+		}
+
+		if ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// newExpenditureFromView returns the expenditure in the modal.
+// It fails when the amount is invalid or the date is after today, like dates of messages.
+// Expenditures from messages keep their timestamps to be linked with them.
+func newExpenditureFromView(
+	cb *slackgo.InteractionCallback, ch *channel, cs channelSettings, md addModalMetadata, now time.Time,
+) (*expenditure, error) {
+	a, err := parseAmount(viewValue(cb, blockAmount).Value)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := cs.location()
+	now = now.In(loc)
+
+	date := now
+	if d, err := time.ParseInLocation(dateLayout, viewValue(cb, blockDate).SelectedDate, loc); err == nil &&
+		d.Format(dateLayout) != now.Format(dateLayout) {
+		if d.After(now) {
+			return nil, errFutureDate
+		}
+
+		date = d
+	}
+
+	ex := &expenditure{
+		Channel:       ch.ID,
+		TS:            md.TS,
+		Timestamp:     now,
+		EffectiveDate: date,
+		Category:      viewValue(cb, blockCategory).SelectedOption.Value,
+		Memo:          strings.TrimSpace(viewValue(cb, blockMemo).Value),
+		Payer:         viewValue(cb, blockPayer).SelectedUser,
+		User:          cb.User.ID,
+	}
+
+	if ex.TS == "" {
+		ex.TS = newTS(now)
+		ex.Manual = true
+	}
+
+	ex.setAmount(a)
+
+	return ex, nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	slackgo "github.com/slack-go/slack"
+)
+
+func Test_newExpenditureFromView(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 10, 19, 12, 34, 56, 789000000, time.UTC)
+	ch := &channel{ID: "ch1"}
+
+	cases := map[string]struct {
+		state string
+		md    addModalMetadata
+		err   error
+		check func(t *testing.T, ex *expenditure)
+	}{
+		"today": {
+			state: `{"amount":{"amount":{"value":"1200"}},"date":{"date":{"selected_date":"2026-10-19"}},` +
+				`"category":{"category":{"selected_option":{"value":"food"}}},"memo":{"memo":{"value":" lunch "}},` +
+				`"payer":{"payer":{"selected_user":"U2"}}}`,
+			check: func(t *testing.T, ex *expenditure) {
+				if ex.Amount != 1200 || ex.Category != "food" || ex.Memo != "lunch" || ex.Payer != "U2" {
+					t.Errorf("incorrect expenditure: %+v", ex)
+				}
+
+				if !ex.Timestamp.Equal(now) || ex.TS != "1792413296.789000" || !ex.Manual {
+					t.Errorf("expenditure of today should be stamped now: %v, %s", ex.Timestamp, ex.TS)
+				}
+			},
+		},
+		"backdated": {
+			state: `{"amount":{"amount":{"value":"USD 12.5"}},"date":{"date":{"selected_date":"2026-09-30"}}}`,
+			check: func(t *testing.T, ex *expenditure) {
+				if ex.EffectiveDate.Format("2006-01-02") != "2026-09-30" || ex.key()[:7] != "2026-09" {
+					t.Errorf("incorrect effective date: %v", ex.EffectiveDate)
+				}
+
+				if !ex.Timestamp.Equal(now) || !ex.backdated() {
+					t.Errorf("backdated expenditure should be stamped now: %v", ex.Timestamp)
+				}
+
+				if ex.OriginalAmount != 12.5 || ex.Currency != "USD" {
+					t.Errorf("incorrect amount: %+v", ex)
+				}
+			},
+		},
+		"from message": {
+			state: `{"amount":{"amount":{"value":"800"}}}`,
+			md:    addModalMetadata{Channel: "ch1", TS: "1790000000.000100"},
+			check: func(t *testing.T, ex *expenditure) {
+				if ex.TS != "1790000000.000100" || ex.Manual {
+					t.Errorf("expenditure should be linked with the message: %+v", ex)
+				}
+			},
+		},
+		"invalid amount": {
+			state: `{"amount":{"amount":{"value":"lunch"}}}`,
+			err:   errNotExpenditureMessage,
+		},
+		"future date": {
+			state: `{"amount":{"amount":{"value":"800"}},"date":{"date":{"selected_date":"2026-10-20"}}}`,
+			err:   errFutureDate,
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var cb slackgo.InteractionCallback
+			if err := json.Unmarshal([]byte(`{"type":"view_submission","view":{"state":{"values":`+c.state+`}}}`), &cb); err != nil {
+				t.Fatalf("json.Unmarshal: %v", err)
+			}
+
+			ex, err := newExpenditureFromView(&cb, ch, channelSettings{Timezone: "UTC"}, c.md, now)
+			if !errors.Is(err, c.err) {
+				t.Fatalf("incorrect error: %v", err)
+			}
+
+			if c.check != nil {
+				c.check(t, ex)
+			}
+		})
+	}
+}
+
+func Test_addModalBlocks(t *testing.T) {
+	t.Parallel()
+
+	withChannel := addModalBlocks(langEN, channelSettings{}, addModalMetadata{Channel: "ch1"}, "U1", "")
+	global := addModalBlocks(langEN, channelSettings{}, addModalMetadata{}, "U1", "")
+
+	if len(global) != len(withChannel)+1 || global[0].BlockID != blockChannel {
+		t.Errorf("modal from the global shortcut should have a channel input")
+	}
+
+	for _, b := range withChannel {
+		if b.BlockID == blockPayer && b.Element.InitialUser != "U1" {
+			t.Errorf("payer should default to the user: %s", b.Element.InitialUser)
+		}
+	}
+}
+
+func Test_commandProcessor_processAdd(t *testing.T) {
+	t.Parallel()
+
+	mock := newSlackMock()
+	p := &commandProcessor{interactions: &interactionProcessor{&eventProcessor{slack: mock}}}
+	c := slackgo.SlashCommand{ChannelID: "ch1", UserID: "U1", TriggerID: "trigger"}
+
+	msg, err := p.processAdd(context.Background(), c, langEN, nil, nil)
+	if err != nil || msg == nil || msg.Text != langEN.t(msgSetBudgetFirst) {
+		t.Errorf("channels without budget should be rejected: %v, %v", msg, err)
+	}
+
+	msg, err = p.processAdd(context.Background(), c, langEN, &channel{ID: "ch1"}, []string{"extra"})
+	if err != nil || msg == nil || msg.Text != langEN.t(msgUsage) {
+		t.Errorf("extra arguments should be rejected: %v, %v", msg, err)
+	}
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"io/ioutil"
+	"log/slog"
 	"net/http"
 
 	"github.com/slack-go/slack"
@@ -10,15 +12,49 @@ import (
 )
 
 type handler struct {
-	eventProcessor   *eventProcessor
-	commandProcessor *commandProcessor
+	eventProcessor       *eventProcessor
+	commandProcessor     *commandProcessor
+	interactionProcessor *interactionProcessor
+	// Log request bodies with user contents redacted at debug level.
+	logBody bool
+	// Scope requests to their workspaces when the app is installed to multiple ones.
+	teams bool
+	// Install flow for multiple workspaces. Nil for a single workspace.
+	oauth *oauthHandler
+}
+
+// withTeam scopes ctx to the workspace of the request if the app serves multiple ones.
+func (h *handler) withTeam(ctx context.Context, id, enterpriseID string) context.Context {
+	if !h.teams {
+		return ctx
+	}
+
+	return withTeam(ctx, id, enterpriseID)
+}
+
+// eventLogAttrs returns attributes to correlate logs with the Slack event.
+func eventLogAttrs(ev slackevents.EventsAPIEvent) []slog.Attr {
+	attrs := []slog.Attr{slog.String("teamId", ev.TeamID), slog.String("eventType", ev.InnerEvent.Type)}
+
+	if cb, ok := ev.Data.(*slackevents.EventsAPICallbackEvent); ok {
+		attrs = append(attrs, slog.String("eventId", cb.EventID))
+	}
+
+	if mev, ok := ev.InnerEvent.Data.(*slackevents.MessageEvent); ok {
+		attrs = append(attrs, slog.String("channelId", mev.Channel))
+	}
+
+	return attrs
 }
 
 func (h *handler) handleEvents(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	var err error
+
+	ctx, end := startSpan(r.Context(), "handleEvents")
+	defer end(&err)
 
 	if contentType := r.Header.Get("Content-Type"); contentType != "application/json" {
-		logger.Printf("Unsupported content type: %s", contentType)
+		logger.WarnContext(ctx, "unsupported content type", slog.String("contentType", contentType))
 		w.WriteHeader(http.StatusUnsupportedMediaType)
 
 		return
@@ -26,18 +62,20 @@ func (h *handler) handleEvents(w http.ResponseWriter, r *http.Request) {
 
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		logger.Printf("ioutil.ReadAll: %v", err)
+		logger.ErrorContext(ctx, "ioutil.ReadAll", slog.Any("err", err))
 		w.WriteHeader(http.StatusConflict)
 
 		return
 	}
 	defer r.Body.Close()
 
-	logger.Printf("%s", body)
+	if h.logBody {
+		logger.DebugContext(ctx, "request body", slog.String("body", redactBody(body)))
+	}
 
 	ev, err := slackevents.ParseEvent(json.RawMessage(body), slackevents.OptionNoVerifyToken())
 	if err != nil {
-		logger.Printf("slackevents.ParseEvent: %v", err)
+		logger.ErrorContext(ctx, "slackevents.ParseEvent", slog.Any("err", err))
 		w.WriteHeader(http.StatusInternalServerError)
 
 		return
@@ -49,8 +87,11 @@ func (h *handler) handleEvents(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.eventProcessor.process(ctx, ev); err != nil {
-		logger.Printf("h.eventProcessor.process: %v", err)
+	ctx = withLogAttrs(ctx, eventLogAttrs(ev)...)
+	ctx = h.withTeam(ctx, ev.TeamID, ev.EnterpriseID)
+
+	if err = h.eventProcessor.process(ctx, ev); err != nil {
+		logger.ErrorContext(ctx, "h.eventProcessor.process", slog.Any("err", err))
 		writeErrorHeader(w, err)
 
 		return
@@ -62,13 +103,13 @@ func (h *handler) handleEvents(w http.ResponseWriter, r *http.Request) {
 func (h *handler) handleChallenge(w http.ResponseWriter, body []byte) {
 	var r *slackevents.ChallengeResponse
 	if err := json.Unmarshal(body, &r); err != nil {
-		logger.Printf("json.Unmarshal: %v", err)
+		logger.Error("json.Unmarshal", slog.Any("err", err))
 		w.WriteHeader(http.StatusInternalServerError)
 	} else {
 		w.Header().Set("Content-Type", "text/plain")
 		w.WriteHeader(http.StatusOK)
 		if _, err := w.Write([]byte(r.Challenge)); err != nil {
-			logger.Printf("w.Write: %v", err)
+			logger.Error("w.Write", slog.Any("err", err))
 		}
 	}
 }
@@ -78,23 +119,84 @@ func (h *handler) handleCommands(w http.ResponseWriter, r *http.Request) {
 
 	s, err := slack.SlashCommandParse(r)
 	if err != nil {
-		logger.Printf("slack.SlashCommandParse: %v", err)
+		logger.ErrorContext(ctx, "slack.SlashCommandParse", slog.Any("err", err))
 		w.WriteHeader(http.StatusInternalServerError)
 
 		return
 	}
 
+	ctx = withLogAttrs(ctx,
+		slog.String("teamId", s.TeamID), slog.String("channelId", s.ChannelID), slog.String("command", s.Command))
+	ctx = h.withTeam(ctx, s.TeamID, s.EnterpriseID)
+
 	resp, err := h.commandProcessor.process(ctx, s)
 	if err != nil {
-		logger.Printf("h.commandProcessor.process: %v", err)
+		logger.ErrorContext(ctx, "h.commandProcessor.process", slog.Any("err", err))
+		writeErrorHeader(w, err)
+
+		return
+	}
+
+	if resp == nil {
+		w.WriteHeader(http.StatusOK)
+
+		return
+	}
+
+	b, err := json.Marshal(resp)
+	if err != nil {
+		logger.ErrorContext(ctx, "json.Marshal", slog.Any("err", err))
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(b); err != nil {
+		logger.ErrorContext(ctx, "w.Write", slog.Any("err", err))
+	}
+}
+
+func (h *handler) handleInteractions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := r.ParseForm(); err != nil {
+		logger.ErrorContext(ctx, "r.ParseForm", slog.Any("err", err))
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	var cb slack.InteractionCallback
+	if err := json.Unmarshal([]byte(r.PostForm.Get("payload")), &cb); err != nil {
+		logger.ErrorContext(ctx, "json.Unmarshal", slog.Any("err", err))
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	ctx = withLogAttrs(ctx, interactionLogAttrs(&cb)...)
+	ctx = h.withTeam(ctx, cb.Team.ID, cb.Enterprise.ID)
+
+	resp, err := h.interactionProcessor.process(ctx, &cb)
+	if err != nil {
+		logger.ErrorContext(ctx, "h.interactionProcessor.process", slog.Any("err", err))
 		writeErrorHeader(w, err)
 
 		return
 	}
 
+	if resp == nil {
+		w.WriteHeader(http.StatusOK)
+
+		return
+	}
+
 	b, err := json.Marshal(resp)
 	if err != nil {
-		logger.Printf("json.Marshal: %v", err)
+		logger.ErrorContext(ctx, "json.Marshal", slog.Any("err", err))
 		w.WriteHeader(http.StatusInternalServerError)
 
 		return
@@ -104,6 +206,15 @@ func (h *handler) handleCommands(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 
 	if _, err := w.Write(b); err != nil {
-		logger.Printf("w.Write: %v", err)
+		logger.ErrorContext(ctx, "w.Write", slog.Any("err", err))
+	}
+}
+
+// interactionLogAttrs returns attributes to correlate logs with the interaction.
+func interactionLogAttrs(cb *slack.InteractionCallback) []slog.Attr {
+	return []slog.Attr{
+		slog.String("teamId", cb.Team.ID),
+		slog.String("channelId", cb.Channel.ID),
+		slog.String("interactionType", string(cb.Type)),
 	}
 }
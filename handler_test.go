@@ -2,10 +2,12 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/nownabe/moneysaver/slack"
 )
@@ -16,7 +18,7 @@ func Test_handler_challenge(t *testing.T) {
 	ep := &eventProcessor{
 		slack: newSlackMock(),
 	}
-	h := &handler{ep, nil}
+	h := &handler{eventProcessor: ep}
 
 	body := bytes.NewBufferString(`{"challenge":"challengetoken","type":"url_verification"}`)
 	req := httptest.NewRequest(http.MethodPost, "/", body)
@@ -93,7 +95,7 @@ func Test_event_handler(t *testing.T) {
 				expenditureRepo: &expenditureRepo{fs},
 			}
 
-			h := &handler{ep, nil}
+			h := &handler{eventProcessor: ep}
 			defer flushStore(t)
 
 			body := bytes.NewBufferString(c.requestBody)
@@ -107,6 +109,9 @@ func Test_event_handler(t *testing.T) {
 				t.Errorf("status code should be %d, but %d", c.code, rec.Code)
 			}
 
+			// Waits for replies after acknowledgement.
+			_ = ep.Close()
+
 			if c.reqs != nil {
 				m, _ := mock.(*slackMock)
 				assertReqs(t, c.reqs, m.requests())
@@ -114,3 +119,32 @@ func Test_event_handler(t *testing.T) {
 		})
 	}
 }
+
+func Test_eventProcessor_Close(t *testing.T) {
+	t.Parallel()
+
+	p := &eventProcessor{closing: make(chan struct{})}
+
+	started := make(chan struct{})
+	p.goBackground(context.Background(), "test", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+
+		return ctx.Err()
+	})
+
+	<-started
+
+	closed := make(chan struct{})
+
+	go func() {
+		_ = p.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close should cancel processing after acknowledgement")
+	}
+}
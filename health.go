@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/nownabe/moneysaver/slack"
+	"google.golang.org/api/iterator"
+)
+
+const readinessTimeout = 5 * time.Second
+
+// Build metadata injected at link time, e.g.
+// go build -ldflags "-X main.version=v1.0.0 -X main.commit=abc1234 -X main.buildDate=2026-01-01T00:00:00Z"
+var (
+	version   = "dev"
+	commit    = "unknown"
+	buildDate = "unknown"
+)
+
+type readinessCheck struct {
+	name  string
+	check func(context.Context) error
+}
+
+// healthHandler serves probes which don't require Slack signatures.
+type healthHandler struct {
+	checks []readinessCheck
+}
+
+// newHealthHandler checks the Slack token only if sc is given,
+// since workspaces have their own tokens when the app is installed to multiple ones.
+func newHealthHandler(fs *firestore.Client, sc slack.Client) *healthHandler {
+	checks := []readinessCheck{{name: "storage", check: storageCheck(fs)}}
+
+	if sc != nil {
+		checks = append(checks, readinessCheck{name: "slack", check: slackCheck(sc)})
+	}
+
+	return &healthHandler{checks: checks}
+}
+
+func storageCheck(fs *firestore.Client) func(context.Context) error {
+	return func(ctx context.Context) error {
+		_, err := fs.Collection(collectionName).Limit(1).Documents(ctx).Next()
+		if err != nil && !errors.Is(err, iterator.Done) {
+			return fmt.Errorf("fs.Collection.Documents: %w", err)
+		}
+
+		return nil
+	}
+}
+
+func slackCheck(sc slack.Client) func(context.Context) error {
+	return func(ctx context.Context) error {
+		if _, err := sc.AuthTest(ctx); err != nil {
+			return fmt.Errorf("sc.AuthTest: %w", err)
+		}
+
+		return nil
+	}
+}
+
+func (h *healthHandler) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (h *healthHandler) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	code := http.StatusOK
+	status := "ok"
+	checks := make(map[string]string, len(h.checks))
+
+	for _, c := range h.checks {
+		if err := c.check(ctx); err != nil {
+			logger.WarnContext(ctx, "readiness check failed", slog.String("check", c.name), slog.Any("err", err))
+
+			code = http.StatusServiceUnavailable
+			status = "unavailable"
+			// The endpoint isn't authenticated, so details are only logged.
+			checks[c.name] = "fail"
+
+			continue
+		}
+
+		checks[c.name] = "ok"
+	}
+
+	writeJSON(w, code, map[string]interface{}{"status": status, "checks": checks})
+}
+
+func (h *healthHandler) handleVersion(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"version":   version,
+		"commit":    commit,
+		"buildDate": buildDate,
+		"goVersion": runtime.Version(),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		logger.Error("json.Marshal", slog.Any("err", err))
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	if _, err := w.Write(b); err != nil {
+		logger.Error("w.Write", slog.Any("err", err))
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_router_probes(t *testing.T) {
+	t.Parallel()
+
+	ok := func(context.Context) error { return nil }
+	ng := func(context.Context) error { return errors.New("unreachable") }
+
+	cases := map[string]struct {
+		method string
+		path   string
+		checks []readinessCheck
+		code   int
+	}{
+		"healthz":       {method: http.MethodGet, path: "/healthz", code: http.StatusOK},
+		"version":       {method: http.MethodGet, path: "/version", code: http.StatusOK},
+		"ready":         {method: http.MethodGet, path: "/readyz", checks: []readinessCheck{{"storage", ok}, {"slack", ok}}, code: http.StatusOK},
+		"not ready":     {method: http.MethodGet, path: "/readyz", checks: []readinessCheck{{"storage", ok}, {"slack", ng}}, code: http.StatusServiceUnavailable},
+		"events signed": {method: http.MethodPost, path: "/", code: http.StatusBadRequest},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			r := newRouter(&handler{}, &healthHandler{checks: c.checks}, newVerifier("secret"))
+
+			req := httptest.NewRequest(c.method, c.path, nil)
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != c.code {
+				t.Errorf("status code should be %d, but %d", c.code, rec.Code)
+			}
+		})
+	}
+}
+
+func Test_healthHandler_readyz(t *testing.T) {
+	t.Parallel()
+
+	h := &healthHandler{checks: []readinessCheck{
+		{"storage", func(context.Context) error { return nil }},
+		{"slack", func(context.Context) error { return errors.New("invalid_auth") }},
+	}}
+
+	rec := httptest.NewRecorder()
+	h.handleReadyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var body struct {
+		Status string            `json:"status"`
+		Checks map[string]string `json:"checks"`
+	}
+
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+
+	if body.Status != "unavailable" || body.Checks["storage"] != "ok" || body.Checks["slack"] != "fail" {
+		t.Errorf("incorrect readiness: %s", rec.Body.String())
+	}
+}
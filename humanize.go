@@ -5,7 +5,19 @@ import (
 	"strings"
 )
 
+var currencyPrefixes = map[string]string{
+	"JPY": "¥",
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+}
+
 func humanize(n int64) string {
+	var sign string
+	if n < 0 {
+		sign, n = "-", -n
+	}
+
 	s := fmt.Sprint(n)
 	l := (len(s) + 3 - 1) / 3
 	parts := make([]string, l)
@@ -18,5 +30,16 @@ func humanize(n int64) string {
 		}
 		parts[i] = s[start:end]
 	}
-	return "¥" + strings.Join(parts, ",")
+	return "¥" + sign + strings.Join(parts, ",")
+}
+
+// humanizeIn formats n in the currency, e.g. `$1,234` or `CHF 1,234`.
+func humanizeIn(n int64, cur string) string {
+	s := strings.TrimPrefix(humanize(n), "¥")
+
+	if p, ok := currencyPrefixes[cur]; ok {
+		return p + s
+	}
+
+	return cur + " " + s
 }
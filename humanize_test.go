@@ -15,6 +15,8 @@ func Test_humanize(t *testing.T) {
 		{n: 1234, e: "¥1,234"},
 		{n: 123456, e: "¥123,456"},
 		{n: 1234567, e: "¥1,234,567"},
+		{n: -500, e: "¥-500"},
+		{n: -1234, e: "¥-1,234"},
 	}
 
 	for _, c := range cases {
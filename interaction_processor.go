@@ -0,0 +1,499 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nownabe/moneysaver/slack"
+	slackgo "github.com/slack-go/slack"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Action IDs of buttons and menus, and callback IDs of views.
+// Actions on an expenditure have its key as the block ID.
+const (
+	actionUndo       = "expenditure.undo"
+	actionEdit       = "expenditure.edit"
+	actionCategorize = "expenditure.categorize"
+	actionRestore    = "expenditure.restore"
+
+	viewEditAmount = "expenditure.editAmount"
+
+	blockAmount = "amount"
+)
+
+type actionHandler func(p *interactionProcessor, ctx context.Context, cb *slackgo.InteractionCallback, a *slackgo.BlockAction) error
+
+// actionHandlers handle block_actions by action ID.
+var actionHandlers = map[string]actionHandler{
+	actionUndo:       (*interactionProcessor).processUndo,
+	actionEdit:       (*interactionProcessor).processEdit,
+	actionCategorize: (*interactionProcessor).processCategorize,
+	actionRestore:    (*interactionProcessor).processRestore,
+}
+
+type viewHandler func(p *interactionProcessor, ctx context.Context, cb *slackgo.InteractionCallback) (*slackgo.ViewSubmissionResponse, error)
+
+// viewHandlers handle view_submission by callback ID.
+var viewHandlers = map[string]viewHandler{
+	viewEditAmount: (*interactionProcessor).submitEditAmount,
+	viewAdd:        (*interactionProcessor).submitAdd,
+}
+
+type shortcutHandler func(p *interactionProcessor, ctx context.Context, cb *slackgo.InteractionCallback) error
+
+// shortcutHandlers handle global and message shortcuts by callback ID.
+var shortcutHandlers = map[string]shortcutHandler{
+	shortcutAdd:            (*interactionProcessor).processAddShortcut,
+	shortcutAddFromMessage: (*interactionProcessor).processAddShortcut,
+}
+
+// interactionName returns the action ID or the callback ID for metrics.
+func interactionName(cb *slackgo.InteractionCallback) string {
+	var (
+		name string
+		ok   bool
+	)
+
+	switch cb.Type {
+	case slackgo.InteractionTypeBlockActions:
+		if len(cb.ActionCallback.BlockActions) > 0 {
+			name = cb.ActionCallback.BlockActions[0].ActionID
+			_, ok = actionHandlers[name]
+		}
+	case slackgo.InteractionTypeViewSubmission:
+		name = cb.View.CallbackID
+		_, ok = viewHandlers[name]
+	case slackgo.InteractionTypeShortcut, slackgo.InteractionTypeMessageAction:
+		name = cb.CallbackID
+		_, ok = shortcutHandlers[name]
+	}
+
+	if !ok {
+		return "unknown"
+	}
+
+	return name
+}
+
+// interactionProcessor processes buttons, menus, modals and shortcuts.
+// It shares repositories and replies with eventProcessor.
+type interactionProcessor struct {
+	*eventProcessor
+}
+
+// process returns the response body, which is nil or a view submission response.
+func (p *interactionProcessor) process(ctx context.Context, cb *slackgo.InteractionCallback) (_ interface{}, err error) {
+	name := interactionName(cb)
+
+	defer observeInteraction(string(cb.Type), name, time.Now(), &err)
+
+	ctx, end := startSpan(ctx, "processInteraction",
+		attribute.String("interaction.type", string(cb.Type)), attribute.String("interaction.name", name))
+	defer end(&err)
+
+	ctx = withActor(ctx, cb.User.ID, cb.TriggerID)
+
+	switch cb.Type {
+	case slackgo.InteractionTypeBlockActions:
+		for _, a := range cb.ActionCallback.BlockActions {
+			h, ok := actionHandlers[a.ActionID]
+			if !ok {
+				logger.WarnContext(ctx, "unknown action", slog.String("actionId", a.ActionID))
+
+				continue
+			}
+
+			if err := h(p, ctx, cb, a); err != nil {
+				return nil, wrap(http.StatusInternalServerError, "action "+a.ActionID+": %w", err)
+			}
+		}
+	case slackgo.InteractionTypeViewSubmission:
+		h, ok := viewHandlers[cb.View.CallbackID]
+		if !ok {
+			logger.WarnContext(ctx, "unknown view", slog.String("callbackId", cb.View.CallbackID))
+
+			return nil, nil
+		}
+
+		resp, err := h(p, ctx, cb)
+		if err != nil {
+			return nil, wrap(http.StatusInternalServerError, "view "+cb.View.CallbackID+": %w", err)
+		}
+
+		// Avoid a typed nil, which is encoded as null.
+		if resp != nil {
+			return resp, nil
+		}
+	case slackgo.InteractionTypeShortcut, slackgo.InteractionTypeMessageAction:
+		h, ok := shortcutHandlers[cb.CallbackID]
+		if !ok {
+			logger.WarnContext(ctx, "unknown shortcut", slog.String("callbackId", cb.CallbackID))
+
+			return nil, nil
+		}
+
+		if err := h(p, ctx, cb); err != nil {
+			return nil, wrap(http.StatusInternalServerError, "shortcut "+cb.CallbackID+": %w", err)
+		}
+	}
+
+	return nil, nil
+}
+
+// findChannel returns the channel with the config file applied.
+func (p *interactionProcessor) findChannel(ctx context.Context, chID string) (*channel, channelSettings, error) {
+	ch, err := p.channelRepo.findByID(ctx, chID)
+	if err != nil {
+		return nil, channelSettings{}, fmt.Errorf("p.channelRepo.findByID: %w", err)
+	}
+
+	cs := p.settings.get().channel(ch.ID)
+	cs.applyTo(ch)
+
+	return ch, cs, nil
+}
+
+// findExpenditure returns the expenditure of the key in the channel's timezone.
+func (p *interactionProcessor) findExpenditure(
+	ctx context.Context, ch *channel, cs channelSettings, key string,
+) (*expenditure, error) {
+	ex, err := p.expenditureRepo.findByKey(ctx, ch.ID, key)
+	if err != nil {
+		return nil, fmt.Errorf("p.expenditureRepo.findByKey: %w", err)
+	}
+
+	ex.in(cs.location())
+
+	return ex, nil
+}
+
+// processUndo moves the expenditure to the trash. Expenditures already deleted are ignored.
+func (p *interactionProcessor) processUndo(
+	ctx context.Context, cb *slackgo.InteractionCallback, a *slackgo.BlockAction,
+) error {
+	ch, cs, err := p.findChannel(ctx, cb.Channel.ID)
+	if errors.Is(err, errNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	ex, err := p.findExpenditure(ctx, ch, cs, a.BlockID)
+	if errors.Is(err, errNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if !ch.canDelete(cb.User.ID, ex) {
+		return p.replyNotAllowed(ctx, ch, cb.User.ID, msgCannotDeleteOthers)
+	}
+
+	if err := p.expenditureRepo.delete(ctx, ex); errors.Is(err, errNotFound) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("p.expenditureRepo.delete: %w", err)
+	}
+
+	expendituresTotal.WithLabelValues(string(opDeleted)).Inc()
+
+	p.replyLater(ctx, ch, cs, cb.User.ID, ex, opDeleted)
+
+	return nil
+}
+
+// processRestore takes the expenditure back from the trash. Expenditures not in the trash are ignored.
+func (p *interactionProcessor) processRestore(
+	ctx context.Context, cb *slackgo.InteractionCallback, a *slackgo.BlockAction,
+) error {
+	ch, cs, err := p.findChannel(ctx, cb.Channel.ID)
+	if errors.Is(err, errNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	ex, err := p.expenditureRepo.findDeleted(ctx, ch.ID, a.BlockID)
+	if errors.Is(err, errNotFound) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("p.expenditureRepo.findDeleted: %w", err)
+	}
+
+	ex.in(cs.location())
+
+	if !ch.canDelete(cb.User.ID, ex) {
+		return p.replyNotAllowed(ctx, ch, cb.User.ID, msgCannotRestoreOthers)
+	}
+
+	if err := p.expenditureRepo.restore(ctx, ex); errors.Is(err, errNotFound) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("p.expenditureRepo.restore: %w", err)
+	}
+
+	expendituresTotal.WithLabelValues(string(opRestored)).Inc()
+
+	p.replyLater(ctx, ch, cs, cb.User.ID, ex, opRestored)
+
+	return nil
+}
+
+// replyNotAllowed tells only the user that the expenditure is others' and who can change it.
+func (p *interactionProcessor) replyNotAllowed(ctx context.Context, ch *channel, userID string, m message) error {
+	l := langFor(ctx, p.userRepo, ch, userID)
+
+	r := &slack.ChatPostEphemeralReq{
+		Channel: ch.ID,
+		User:    userID,
+		Text:    l.t(m, ch.CanDeleteOthers.label(l)),
+	}
+
+	if err := p.slack.ChatPostEphemeral(ctx, r); err != nil {
+		return fmt.Errorf("p.slack.ChatPostEphemeral: %w", err)
+	}
+
+	return nil
+}
+
+// processEdit opens a modal to edit the amount.
+// trigger_id expires in 3 seconds, so the modal is opened with the language and the amount in the value of the button
+// without reading storage. Permissions are checked on submission.
+func (p *interactionProcessor) processEdit(
+	ctx context.Context, cb *slackgo.InteractionCallback, a *slackgo.BlockAction,
+) error {
+	l, amount, ok := parseEditValue(a.Value)
+	if !ok {
+		// Replies posted by older versions have no value.
+		ch, cs, err := p.findChannel(ctx, cb.Channel.ID)
+		if errors.Is(err, errNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		ex, err := p.findExpenditure(ctx, ch, cs, a.BlockID)
+		if errors.Is(err, errNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		l, amount = langFor(ctx, p.userRepo, ch, cb.User.ID), editableAmount(ex)
+	}
+
+	r := &slack.ViewsOpenReq{
+		TriggerID: cb.TriggerID,
+		View: &slack.View{
+			Type:            slack.ViewModal,
+			CallbackID:      viewEditAmount,
+			PrivateMetadata: cb.Channel.ID + "/" + a.BlockID,
+			Title:           slack.PlainText(l.t(msgEditAmountTitle)),
+			Submit:          slack.PlainText(l.t(msgSave)),
+			Close:           slack.PlainText(l.t(msgCancel)),
+			Blocks: []*slack.Block{
+				{
+					Type:    slack.BlockInput,
+					BlockID: blockAmount,
+					Label:   slack.PlainText(l.t(msgFieldAmount)),
+					Element: &slack.Element{
+						Type:         slack.ElementPlainTextInput,
+						ActionID:     blockAmount,
+						InitialValue: amount,
+					},
+				},
+			},
+		},
+	}
+
+	if err := p.slack.ViewsOpen(ctx, r); err != nil {
+		return fmt.Errorf("p.slack.ViewsOpen: %w", err)
+	}
+
+	return nil
+}
+
+// editableAmount returns the amount as posted.
+func editableAmount(ex *expenditure) string {
+	if ex.Currency != "" {
+		return formatAmount(ex.OriginalAmount, ex.Currency)
+	}
+
+	return strconv.FormatInt(ex.Amount, 10)
+}
+
+// editValue returns the value of the edit button like `en 12.5 USD`.
+func editValue(l lang, ex *expenditure) string {
+	return string(l) + " " + editableAmount(ex)
+}
+
+func parseEditValue(v string) (lang, string, bool) {
+	l, amount, ok := strings.Cut(v, " ")
+	if !ok || amount == "" {
+		return "", "", false
+	}
+
+	return lang(l), amount, true
+}
+
+// submitEditAmount updates the amount, or returns errors to show in the modal.
+func (p *interactionProcessor) submitEditAmount(
+	ctx context.Context, cb *slackgo.InteractionCallback,
+) (*slackgo.ViewSubmissionResponse, error) {
+	chID, key, ok := strings.Cut(cb.View.PrivateMetadata, "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid private metadata: %q", cb.View.PrivateMetadata)
+	}
+
+	ch, cs, err := p.findChannel(ctx, chID)
+	if errors.Is(err, errNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	l := langFor(ctx, p.userRepo, ch, cb.User.ID)
+
+	a, err := parseAmount(viewValue(cb, blockAmount).Value)
+	if err != nil {
+		return slackgo.NewErrorsViewSubmissionResponse(map[string]string{blockAmount: l.t(msgInvalidAmount)}), nil
+	}
+
+	ex, err := p.findExpenditure(ctx, ch, cs, key)
+	if errors.Is(err, errNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if !ch.canDelete(cb.User.ID, ex) {
+		return slackgo.NewErrorsViewSubmissionResponse(
+			map[string]string{blockAmount: l.t(msgCannotEditOthers, ch.CanDeleteOthers.label(l))}), nil
+	}
+
+	ex.setAmount(a)
+
+	if err := p.rates.convert(ch, ex); err != nil {
+		return slackgo.NewErrorsViewSubmissionResponse(map[string]string{blockAmount: l.t(msgUnknownRate, ex.Currency)}), nil
+	}
+
+	if err := p.expenditureRepo.add(ctx, ex); err != nil {
+		return nil, fmt.Errorf("p.expenditureRepo.add: %w", err)
+	}
+
+	expendituresTotal.WithLabelValues(string(opUpdated)).Inc()
+
+	p.replyLater(ctx, ch, cs, cb.User.ID, ex, opUpdated)
+
+	return nil, nil
+}
+
+// processCategorize sets the selected category.
+func (p *interactionProcessor) processCategorize(
+	ctx context.Context, cb *slackgo.InteractionCallback, a *slackgo.BlockAction,
+) error {
+	ch, cs, err := p.findChannel(ctx, cb.Channel.ID)
+	if errors.Is(err, errNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// Options may be outdated or forged.
+	if !slices.Contains(cs.categories(), a.SelectedOption.Value) {
+		logger.WarnContext(ctx, "unknown category is selected", slog.String("category", a.SelectedOption.Value))
+		return nil
+	}
+
+	ex, err := p.findExpenditure(ctx, ch, cs, a.BlockID)
+	if errors.Is(err, errNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if !ch.canDelete(cb.User.ID, ex) {
+		return p.replyNotAllowed(ctx, ch, cb.User.ID, msgCannotEditOthers)
+	}
+
+	ex.Category = a.SelectedOption.Value
+
+	if err := p.expenditureRepo.add(ctx, ex); err != nil {
+		return fmt.Errorf("p.expenditureRepo.add: %w", err)
+	}
+
+	return nil
+}
+
+// viewValue returns the state of the input block whose action ID is the same as the block ID.
+func viewValue(cb *slackgo.InteractionCallback, blockID string) slackgo.BlockAction {
+	if cb.View.State == nil {
+		return slackgo.BlockAction{}
+	}
+
+	return cb.View.State.Values[blockID][blockID]
+}
+
+// expenditureActions returns buttons and a menu on the reply to the expenditure.
+func expenditureActions(l lang, cs channelSettings, ex *expenditure) *slack.Block {
+	categories := cs.categories()
+
+	sel := &slack.Element{
+		Type:        slack.ElementStaticSelect,
+		ActionID:    actionCategorize,
+		Placeholder: slack.PlainText(l.t(msgActionCategorize)),
+		Options:     make([]*slack.OptionObject, 0, len(categories)),
+	}
+
+	for _, c := range categories {
+		o := &slack.OptionObject{Text: slack.PlainText(c), Value: c}
+		sel.Options = append(sel.Options, o)
+
+		if c == ex.Category {
+			sel.InitialOption = o
+		}
+	}
+
+	return &slack.Block{
+		Type:    slack.BlockActions,
+		BlockID: ex.key(),
+		Elements: []interface{}{
+			&slack.Element{
+				Type:     slack.ElementButton,
+				ActionID: actionUndo,
+				Text:     slack.PlainText(l.t(msgActionUndo)),
+				Style:    slack.StyleDanger,
+			},
+			&slack.Element{
+				Type:     slack.ElementButton,
+				ActionID: actionEdit,
+				Text:     slack.PlainText(l.t(msgActionEdit)),
+				Value:    editValue(l, ex),
+			},
+			sel,
+		},
+	}
+}
+
+// restoreActions returns the button on the reply to the deleted expenditure.
+func restoreActions(l lang, ex *expenditure) *slack.Block {
+	return &slack.Block{
+		Type:    slack.BlockActions,
+		BlockID: ex.key(),
+		Elements: []interface{}{
+			&slack.Element{
+				Type:     slack.ElementButton,
+				ActionID: actionRestore,
+				Text:     slack.PlainText(l.t(msgActionUndo)),
+			},
+		},
+	}
+}
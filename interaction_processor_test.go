@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nownabe/moneysaver/slack"
+	slackgo "github.com/slack-go/slack"
+)
+
+func Test_parseExpenditureKey(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		key     string
+		month   string
+		ts      string
+		wantErr bool
+	}{
+		"valid":         {key: "2026-10/1234.5678", month: "2026-10", ts: "1234.5678"},
+		"no separator":  {key: "1234.5678", wantErr: true},
+		"invalid month": {key: "2026-13/1234.5678", wantErr: true},
+		"empty ts":      {key: "2026-10/", wantErr: true},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			month, ts, err := parseExpenditureKey(c.key)
+			if c.wantErr != (err != nil) {
+				t.Fatalf("incorrect error: %v", err)
+			}
+
+			if month != c.month || ts != c.ts {
+				t.Errorf("incorrect key: %s, %s", month, ts)
+			}
+		})
+	}
+}
+
+func Test_expenditureActions(t *testing.T) {
+	t.Parallel()
+
+	ex := &expenditure{
+		TS:        "1234.5678",
+		Timestamp: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+		Category:  "daily",
+	}
+
+	b := expenditureActions(langEN, channelSettings{}, ex)
+
+	if b.BlockID != "2026-10/1234.5678" {
+		t.Errorf("block ID should be the key: %s", b.BlockID)
+	}
+
+	if len(b.Elements) != 3 {
+		t.Fatalf("incorrect elements: %d", len(b.Elements))
+	}
+
+	sel, _ := b.Elements[2].(*slack.Element)
+	if len(sel.Options) != len(defaultCategories) {
+		t.Errorf("incorrect options: %d", len(sel.Options))
+	}
+
+	if sel.InitialOption == nil || sel.InitialOption.Value != "daily" {
+		t.Errorf("current category should be selected: %v", sel.InitialOption)
+	}
+
+	edit, _ := b.Elements[1].(*slack.Element)
+	if l, amount, ok := parseEditValue(edit.Value); !ok || l != langEN || amount != "0" {
+		t.Errorf("edit button should have the language and the amount: %q", edit.Value)
+	}
+}
+
+func Test_interactionProcessor_process_unknown(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"action":   `{"type":"block_actions","actions":[{"action_id":"unknown"}]}`,
+		"view":     `{"type":"view_submission","view":{"callback_id":"unknown"}}`,
+		"shortcut": `{"type":"shortcut","callback_id":"unknown"}`,
+	}
+
+	for name, payload := range cases {
+		payload := payload
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var cb slackgo.InteractionCallback
+			if err := json.Unmarshal([]byte(payload), &cb); err != nil {
+				t.Fatalf("json.Unmarshal: %v", err)
+			}
+
+			if name := interactionName(&cb); name != "unknown" {
+				t.Errorf("incorrect name: %s", name)
+			}
+
+			p := &interactionProcessor{}
+
+			resp, err := p.process(context.Background(), &cb)
+			if err != nil || resp != nil {
+				t.Errorf("unknown interactions should be ignored: %v, %v", resp, err)
+			}
+		})
+	}
+}
+
+func Test_interactionProcessor_process(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fs := getFirestoreClient(t)
+	mock := newSlackMock()
+
+	defer flushStore(t)
+
+	p := &interactionProcessor{&eventProcessor{
+		slack:           mock,
+		channelRepo:     &channelRepo{fs},
+		expenditureRepo: &expenditureRepo{fs},
+		userRepo:        &userRepo{fs},
+	}}
+
+	if err := p.channelRepo.save(ctx, &channel{ID: "ch1", Budget: 10000}); err != nil {
+		t.Fatalf("p.channelRepo.save: %v", err)
+	}
+
+	ex := &expenditure{Channel: "ch1", TS: "1790000000.000100", Amount: 1200, Timestamp: time.Unix(1790000000, 0)}
+	if err := p.expenditureRepo.add(ctx, ex); err != nil {
+		t.Fatalf("p.expenditureRepo.add: %v", err)
+	}
+
+	process := func(payload string) interface{} {
+		t.Helper()
+
+		var cb slackgo.InteractionCallback
+		if err := json.Unmarshal([]byte(payload), &cb); err != nil {
+			t.Fatalf("json.Unmarshal: %v", err)
+		}
+
+		resp, err := p.process(ctx, &cb)
+		if err != nil {
+			t.Fatalf("p.process: %v", err)
+		}
+
+		return resp
+	}
+
+	key := ex.key()
+
+	process(`{"type":"block_actions","channel":{"id":"ch1"},"actions":[{"action_id":"expenditure.categorize","block_id":"` +
+		key + `","selected_option":{"value":"food"}}]}`)
+
+	if got, _ := p.expenditureRepo.findByKey(ctx, "ch1", key); got == nil || got.Category != "food" {
+		t.Errorf("category should be set: %v", got)
+	}
+
+	process(`{"type":"block_actions","channel":{"id":"ch1"},"actions":[{"action_id":"expenditure.categorize","block_id":"` +
+		key + `","selected_option":{"value":"unknown"}}]}`)
+
+	if got, _ := p.expenditureRepo.findByKey(ctx, "ch1", key); got == nil || got.Category != "food" {
+		t.Errorf("unknown categories should be ignored: %v", got)
+	}
+
+	process(`{"type":"block_actions","channel":{"id":"ch1"},"trigger_id":"trigger","actions":[{"action_id":"expenditure.edit","block_id":"` +
+		key + `"}]}`)
+
+	m, _ := mock.(*slackMock)
+	if len(m.views) != 1 || m.views[0].View.PrivateMetadata != "ch1/"+key {
+		t.Fatalf("edit modal should be opened: %v", m.views)
+	}
+
+	// Opened with the value of the button even if the expenditure is unknown
+	process(`{"type":"block_actions","channel":{"id":"ch2"},"trigger_id":"trigger","actions":[{"action_id":"expenditure.edit","block_id":"` +
+		key + `","value":"en 12.5 USD"}]}`)
+
+	if len(m.views) != 2 || m.views[1].View.PrivateMetadata != "ch2/"+key ||
+		m.views[1].View.Blocks[0].Element.InitialValue != "12.5 USD" || m.views[1].View.Title.Text != langEN.t(msgEditAmountTitle) {
+		t.Fatalf("edit modal should be opened with the value: %+v", m.views[1].View)
+	}
+
+	state := `"state":{"values":{"amount":{"amount":{"value":%q}}}}`
+	view := `{"type":"view_submission","view":{"callback_id":"expenditure.editAmount","private_metadata":"ch1/` + key + `",`
+
+	if resp := process(view + fmt.Sprintf(state, "abc") + `}}`); resp == nil {
+		t.Errorf("invalid amount should be shown as errors")
+	}
+
+	if resp := process(view + fmt.Sprintf(state, "1500") + `}}`); resp != nil {
+		t.Errorf("modal should be closed: %v", resp)
+	}
+
+	if got, _ := p.expenditureRepo.findByKey(ctx, "ch1", key); got == nil || got.Amount != 1500 || got.Category != "food" {
+		t.Errorf("amount should be updated: %v", got)
+	}
+
+	restricted := &channel{ID: "ch1", Budget: 10000, Owners: []string{"U1"}, CanDeleteOthers: roleOwners}
+	if err := p.channelRepo.save(ctx, restricted); err != nil {
+		t.Fatalf("p.channelRepo.save: %v", err)
+	}
+
+	process(`{"type":"block_actions","channel":{"id":"ch1"},"user":{"id":"U2"},"actions":[{"action_id":"expenditure.categorize","block_id":"` +
+		key + `","selected_option":{"value":"daily"}}]}`)
+
+	if got, _ := p.expenditureRepo.findByKey(ctx, "ch1", key); got == nil || got.Category != "food" || len(m.ephemerals) != 1 {
+		t.Errorf("expenditures of others should not be changed without permission: %v, %v", got, m.ephemerals)
+	}
+
+	if resp := process(strings.Replace(view, `{"type"`, `{"user":{"id":"U2"},"type"`, 1) + fmt.Sprintf(state, "0") + `}}`); resp == nil {
+		t.Errorf("amounts of others should not be changed without permission")
+	}
+
+	process(`{"type":"block_actions","channel":{"id":"ch1"},"user":{"id":"U2"},"actions":[{"action_id":"expenditure.undo","block_id":"` + key + `"}]}`)
+
+	if _, err := p.expenditureRepo.findByKey(ctx, "ch1", key); err != nil || len(m.ephemerals) != 2 {
+		t.Errorf("expenditures of others should not be deleted without permission: %v, %v", err, m.ephemerals)
+	}
+
+	process(`{"type":"block_actions","channel":{"id":"ch1"},"user":{"id":"U1"},"actions":[{"action_id":"expenditure.undo","block_id":"` + key + `"}]}`)
+	process(`{"type":"block_actions","channel":{"id":"ch1"},"user":{"id":"U1"},"actions":[{"action_id":"expenditure.undo","block_id":"` + key + `"}]}`)
+
+	if _, err := p.expenditureRepo.findByKey(ctx, "ch1", key); err == nil {
+		t.Errorf("expenditure should be deleted")
+	}
+
+	process(`{"type":"block_actions","channel":{"id":"ch1"},"user":{"id":"U2"},"actions":[{"action_id":"expenditure.restore","block_id":"` + key + `"}]}`)
+
+	if _, err := p.expenditureRepo.findDeleted(ctx, "ch1", key); err != nil || len(m.ephemerals) != 3 {
+		t.Errorf("expenditures of others should not be restored without permission: %v, %v", err, m.ephemerals)
+	}
+
+	process(`{"type":"block_actions","channel":{"id":"ch1"},"user":{"id":"U1"},"actions":[{"action_id":"expenditure.restore","block_id":"` + key + `"}]}`)
+	process(`{"type":"block_actions","channel":{"id":"ch1"},"user":{"id":"U1"},"actions":[{"action_id":"expenditure.restore","block_id":"` + key + `"}]}`)
+
+	if got, err := p.expenditureRepo.findByKey(ctx, "ch1", key); err != nil || got.Amount != 1500 {
+		t.Errorf("expenditure should be restored: %v, %v", got, err)
+	}
+
+	// Updated, deleted and restored once
+	_ = p.Close()
+
+	if len(m.requests()) != 3 {
+		t.Errorf("incorrect replies: %d", len(m.requests()))
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const redacted = "[REDACTED]"
+
+// Replaced in main according to config.
+var logger = newLogger(os.Stdout, slog.LevelInfo, "json")
+
+// Keys of request bodies which may contain user messages or secrets.
+var redactedKeys = map[string]bool{
+	"text":         true,
+	"blocks":       true,
+	"attachments":  true,
+	"files":        true,
+	"token":        true,
+	"authed_users": true,
+}
+
+func newLogger(w io.Writer, level slog.Leveler, format string) *slog.Logger {
+	opts := &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: cloudLoggingAttr,
+	}
+
+	var h slog.Handler
+	if format == "text" {
+		h = slog.NewTextHandler(w, opts)
+	} else {
+		h = slog.NewJSONHandler(w, opts)
+	}
+
+	return slog.New(&contextHandler{h})
+}
+
+// cloudLoggingAttr renames attributes to the special fields of Cloud Logging.
+// https://cloud.google.com/logging/docs/structured-logging#special-payload-fields
+func cloudLoggingAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
+	}
+
+	switch a.Key {
+	case slog.MessageKey:
+		a.Key = "message"
+	case slog.LevelKey:
+		a.Key = "severity"
+
+		if l, ok := a.Value.Any().(slog.Level); ok && l >= slog.LevelWarn && l < slog.LevelError {
+			a.Value = slog.StringValue("WARNING")
+		}
+	}
+
+	return a
+}
+
+type logAttrsKey struct{}
+
+// withLogAttrs returns a context whose logs have the attributes.
+func withLogAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	prev, _ := ctx.Value(logAttrsKey{}).([]slog.Attr)
+
+	merged := make([]slog.Attr, 0, len(prev)+len(attrs))
+	merged = append(merged, prev...)
+	merged = append(merged, attrs...)
+
+	return context.WithValue(ctx, logAttrsKey{}, merged)
+}
+
+// contextHandler adds the chi request ID and attributes in the context.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := middleware.GetReqID(ctx); id != "" {
+		r.AddAttrs(slog.String("requestId", id))
+	}
+
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("traceId", sc.TraceID().String()), slog.String("spanId", sc.SpanID().String()))
+	}
+
+	if attrs, ok := ctx.Value(logAttrsKey{}).([]slog.Attr); ok {
+		r.AddAttrs(attrs...)
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{h.Handler.WithGroup(name)}
+}
+
+// requestLogger logs each request in a structured way instead of chi's text logger.
+func requestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		logger.InfoContext(r.Context(), "request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Int("bytes", ww.BytesWritten()),
+			slog.String("remoteIp", r.RemoteAddr),
+			slog.Duration("latency", time.Since(start)),
+		)
+	})
+}
+
+// redactBody returns the JSON body whose user contents and secrets are redacted.
+func redactBody(body []byte) string {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return redacted
+	}
+
+	b, err := json.Marshal(redact(v))
+	if err != nil {
+		return redacted
+	}
+
+	return string(b)
+}
+
+func redact(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, vv := range v {
+			if redactedKeys[k] {
+				v[k] = redacted
+			} else {
+				v[k] = redact(vv)
+			}
+		}
+	case []interface{}:
+		for i, vv := range v {
+			v[i] = redact(vv)
+		}
+	}
+
+	return v
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func Test_newLogger(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	l := newLogger(&buf, slog.LevelInfo, "json")
+
+	ctx := withLogAttrs(context.Background(), slog.String("teamId", "T0001"))
+	ctx = withLogAttrs(ctx, slog.String("channelId", "C0001"))
+
+	l.WarnContext(ctx, "hello", slog.Int("n", 1))
+	l.DebugContext(ctx, "should not be logged")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("a single JSON entry should be logged: %v: %s", err, buf.String())
+	}
+
+	expect := map[string]interface{}{
+		"severity":  "WARNING",
+		"message":   "hello",
+		"teamId":    "T0001",
+		"channelId": "C0001",
+		"n":         float64(1),
+	}
+
+	for k, v := range expect {
+		if entry[k] != v {
+			t.Errorf("%s should be %v, but %v", k, v, entry[k])
+		}
+	}
+}
+
+func Test_redactBody(t *testing.T) {
+	t.Parallel()
+
+	body := `{"token":"secret","team_id":"T0001","event":{"channel":"C0001","text":"1200","previous_message":{"text":"300"}}}`
+
+	a := redactBody([]byte(body))
+
+	for _, s := range []string{"secret", "1200", "300"} {
+		if strings.Contains(a, s) {
+			t.Errorf("%s should be redacted: %s", s, a)
+		}
+	}
+
+	for _, s := range []string{"T0001", "C0001"} {
+		if !strings.Contains(a, s) {
+			t.Errorf("%s should not be redacted: %s", s, a)
+		}
+	}
+
+	if a := redactBody([]byte("text=1200")); a != redacted {
+		t.Errorf("non-JSON body should be redacted entirely: %s", a)
+	}
+}
@@ -2,7 +2,7 @@ package main
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"time"
@@ -15,11 +15,14 @@ import (
 
 const (
 	timeoutSec = 60
-)
+	// Events processed after acknowledgement are bounded like requests.
+	backgroundTimeout = timeoutSec * time.Second
 
-// Align chi middleware
-// https://github.com/go-chi/chi/blob/b6a2c5a909f66db8b2166b69628fff095ed51adc/middleware/logger.go#L170
-var logger = log.New(os.Stdout, "", log.LstdFlags)
+	settingsWatchInterval = 10 * time.Second
+	recurringInterval     = 10 * time.Minute
+	auditPurgeInterval    = 24 * time.Hour
+	trashPurgeInterval    = 24 * time.Hour
+)
 
 func main() {
 	c, err := newConfig()
@@ -27,48 +30,229 @@ func main() {
 		panic(err)
 	}
 
+	logger = newLogger(os.Stdout, c.LogLevel, c.LogFormat)
+
 	ctx := context.Background()
 
+	tracing, err := setupTracing(ctx, c.TraceExporter, c.TraceSampleRatio, os.Stdout)
+	if err != nil {
+		panic(err)
+	}
+
 	fs, err := firestore.NewClient(ctx, c.ProjectID)
 	if err != nil {
 		panic(err)
 	}
 
+	var secrets *secretBox
+
+	if c.SecretsKeyFile != "" {
+		keys, err := loadKeyring(c.SecretsKeyFile)
+		if err != nil {
+			panic(err)
+		}
+
+		secrets = &secretBox{keys}
+	}
+
+	installations := &installationRepo{fs, secrets}
+
+	// `moneysaver reencrypt` seals stored tokens with the primary key after rotation, and exits.
+	if len(os.Args) > 1 && os.Args[1] == "reencrypt" {
+		n, err := installations.reencrypt(ctx)
+		if err != nil {
+			logger.Error("failed to re-encrypt tokens", slog.Int("updated", n), slog.Any("err", err))
+			os.Exit(1)
+		}
+
+		logger.Info("re-encrypted tokens", slog.Int("updated", n))
+
+		return
+	}
+
+	// `moneysaver migrate <team_id>` copies documents of a single workspace under the team
+	// before switching to multiple workspaces, and exits.
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if len(os.Args) != 3 {
+			logger.Error("usage: moneysaver migrate <team_id>")
+			os.Exit(2)
+		}
+
+		n, err := migrateTeam(ctx, fs, os.Args[2])
+		if err != nil {
+			logger.Error("failed to migrate documents", slog.Int("copied", n), slog.Any("err", err))
+			os.Exit(1)
+		}
+
+		logger.Info("migrated documents", slog.String("team", os.Args[2]), slog.Int("copied", n))
+
+		return
+	}
+
+	// Reloads apply only changed limits, so budgets set with commands are kept.
+	var applied map[string]int64
+
+	bootstrap := func(ctx context.Context, s *settings) error {
+		limits := s.limits(c.Limits)
+
+		if err := bootstrapLimits(ctx, &channelRepo{fs}, changedLimits(applied, limits), c.LimitsDryRun); err != nil {
+			return err
+		}
+
+		applied = limits
+
+		return nil
+	}
+
+	if err := bootstrap(ctx, c.settings); err != nil {
+		panic(err)
+	}
+
+	st := newSettingsStore(c.ConfigFile, c.settings, bootstrap)
+
+	rates, err := loadRateTable(c.RatesFile)
+	if err != nil {
+		panic(err)
+	}
+
+	var (
+		sc    slack.Client
+		teams *teamClients
+		// Client checked by readiness probes
+		hc slack.Client
+	)
+
+	if c.multiWorkspace() {
+		teams = newTeamClients(installations, c.slackOptions()...)
+		sc = teams
+	} else {
+		sc = slack.New(c.SlackBotToken, c.slackOptions()...)
+		hc = sc
+	}
+
 	ep := &eventProcessor{
-		slack:           slack.New(c.SlackBotToken),
+		slack:           sc,
 		channelRepo:     &channelRepo{fs},
 		expenditureRepo: &expenditureRepo{fs},
+		userRepo:        &userRepo{fs},
+		rates:           rates,
+		settings:        st,
+		teams:           teams,
+		closing:         make(chan struct{}),
 	}
 
+	ip := &interactionProcessor{ep}
+
 	cp := &commandProcessor{
-		channelRepo: &channelRepo{fs},
+		channelRepo:     &channelRepo{fs},
+		userRepo:        &userRepo{fs},
+		settings:        st,
+		expenditureRepo: &expenditureRepo{fs},
+		recurringRepo:   &recurringRepo{fs},
+		rates:           rates,
+		interactions:    ip,
+		auditRepo:       &auditRepo{fs},
 	}
 
+	rs := &recurringScheduler{ep, &recurringRepo{fs}}
+
 	h := &handler{
-		eventProcessor:   ep,
-		commandProcessor: cp,
+		eventProcessor:       ep,
+		commandProcessor:     cp,
+		interactionProcessor: ip,
+		logBody:              c.LogBody,
+		teams:                teams != nil,
+	}
+
+	if teams != nil {
+		h.oauth = &oauthHandler{
+			clientID:     c.SlackClientID,
+			clientSecret: c.SlackClientSecret,
+			redirectURL:  c.SlackRedirectURL,
+			repo:         installations,
+			clients:      teams,
+			slackOptions: c.slackOptions(),
+			now:          time.Now,
+		}
 	}
 
-	r := newRouter(h, c.SlackSigningSecret)
+	r := newRouter(h, newHealthHandler(fs, hc), c.verifier())
+
+	srv := newServer(c.addr(), r, c.ShutdownTimeout)
+	// Processing after acknowledgement uses Firestore.
+	srv.addCloser(ep)
+	srv.addCloser(fs)
+	// Flush spans at last
+	srv.addCloser(tracing)
+
+	if c.MetricsPort != "" {
+		srv.addListener(c.metricsAddr(), newMetricsHandler())
+	}
 
-	// Start server
-	if err := http.ListenAndServe(":8080", r); err != nil {
-		logger.Fatalf("failed to listen and serve: %v", err)
+	if c.Transport == transportSocket {
+		sm := slack.NewSocketMode(c.SlackAppToken, c.slackOptions()...)
+		sm.OnError(func(err error) { logger.Warn("socket mode connection error", slog.Any("err", err)) })
+		srv.addWorker(func(ctx context.Context) {
+			if err := sm.Run(ctx, h.handleEnvelope); err != nil {
+				logger.ErrorContext(ctx, "failed to run socket mode", slog.Any("err", err))
+			}
+		})
+	}
+
+	srv.addWorker(func(ctx context.Context) { rs.run(ctx, recurringInterval) })
+
+	if c.AuditRetentionDays > 0 {
+		ap := &auditPurger{&auditRepo{fs}, time.Duration(c.AuditRetentionDays) * 24 * time.Hour}
+		srv.addWorker(func(ctx context.Context) { ap.run(ctx, auditPurgeInterval) })
+	}
+
+	if c.TrashRetentionDays > 0 {
+		tp := &trashPurger{&expenditureRepo{fs}, time.Duration(c.TrashRetentionDays) * 24 * time.Hour}
+		srv.addWorker(func(ctx context.Context) { tp.run(ctx, trashPurgeInterval) })
+	}
+
+	if c.ConfigFile != "" {
+		srv.addWorker(func(ctx context.Context) { st.watch(ctx, settingsWatchInterval) })
+	}
+
+	if err := srv.run(ctx); err != nil {
+		logger.Error("failed to run server", slog.Any("err", err))
+		os.Exit(1)
 	}
 }
 
-func newRouter(h *handler, signingSecret string) http.Handler {
+func newRouter(h *handler, hh *healthHandler, v *verifier) http.Handler {
 	r := chi.NewRouter()
 
 	r.Use(middleware.RequestID)
+	r.Use(tracingMiddleware)
 	r.Use(middleware.RealIP)
-	r.Use(middleware.RequestLogger(&middleware.DefaultLogFormatter{Logger: logger, NoColor: false}))
+	r.Use(requestLogger)
 	r.Use(middleware.Recoverer)
+	r.Use(metricsMiddleware)
 	r.Use(middleware.Timeout(timeoutSec * time.Second))
-	r.Use(slackVerifier(signingSecret))
 
-	r.Post("/", h.handleEvents)
-	r.Post("/commands", h.handleCommands)
+	r.Get("/healthz", hh.handleHealthz)
+	r.Get("/readyz", hh.handleReadyz)
+	r.Get("/version", hh.handleVersion)
+
+	if h.oauth != nil {
+		r.Get("/slack/install", h.oauth.handleInstall)
+		r.Get("/slack/oauth_redirect", h.oauth.handleRedirect)
+	}
+
+	// Requests from Slack can't be verified without the signing secret.
+	if v == nil {
+		return r
+	}
+
+	r.Group(func(r chi.Router) {
+		r.Use(slackVerifier(v))
+
+		r.Post("/", h.handleEvents)
+		r.Post("/commands", h.handleCommands)
+		r.Post("/interactions", h.handleInteractions)
+	})
 
 	return r
 }
@@ -0,0 +1,619 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+type lang string
+
+const (
+	langJA lang = "ja"
+	langEN lang = "en"
+
+	defaultLang = langJA
+)
+
+var langs = []lang{langJA, langEN}
+
+func parseLang(s string) (lang, bool) {
+	for _, l := range langs {
+		if string(l) == s {
+			return l, true
+		}
+	}
+
+	return "", false
+}
+
+type message string
+
+const (
+	msgExpenditureAdded   message = "expenditureAdded"
+	msgExpenditureDeleted message = "expenditureDeleted"
+	msgFieldUsed          message = "fieldUsed"
+	msgFieldDeleted       message = "fieldDeleted"
+	msgFieldRemaining     message = "fieldRemaining"
+	msgFieldTotal         message = "fieldTotal"
+	msgFieldBudget        message = "fieldBudget"
+	msgThresholdCrossed   message = "thresholdCrossed"
+	msgExpenditureUpdated message = "expenditureUpdated"
+	msgBackdated          message = "backdated"
+	msgFieldRollover      message = "fieldRollover"
+	msgFieldYearToDate    message = "fieldYearToDate"
+	msgProcessFailed      message = "processFailed"
+	msgUnknownRate        message = "unknownRate"
+
+	msgActionUndo       message = "actionUndo"
+	msgActionEdit       message = "actionEdit"
+	msgActionCategorize message = "actionCategorize"
+	msgEditAmountTitle  message = "editAmountTitle"
+	msgFieldAmount      message = "fieldAmount"
+	msgSave             message = "save"
+	msgCancel           message = "cancel"
+	msgInvalidAmount    message = "invalidAmount"
+	msgAddTitle         message = "addTitle"
+	msgAdd              message = "add"
+	msgFieldChannel     message = "fieldChannel"
+	msgFieldCategory    message = "fieldCategory"
+	msgFieldMemo        message = "fieldMemo"
+	msgFieldDate        message = "fieldDate"
+	msgFieldPayer       message = "fieldPayer"
+	msgNoBudget         message = "noBudget"
+	msgFutureDate       message = "futureDate"
+
+	msgHomeTitle         message = "homeTitle"
+	msgHomeNoChannels    message = "homeNoChannels"
+	msgHomeRemaining     message = "homeRemaining"
+	msgHomeEmpty         message = "homeEmpty"
+	msgHomeTopCategories message = "homeTopCategories"
+	msgHomeRecent        message = "homeRecent"
+	msgUncategorized     message = "uncategorized"
+	msgHomeRollover      message = "homeRollover"
+
+	msgRecurringUsage     message = "recurringUsage"
+	msgRecurringAdded     message = "recurringAdded"
+	msgRecurringListTitle message = "recurringListTitle"
+	msgRecurringItem      message = "recurringItem"
+	msgRecurringEmpty     message = "recurringEmpty"
+	msgRecurringCanceled  message = "recurringCanceled"
+	msgRecurringNotFound  message = "recurringNotFound"
+	msgRecurringRecorded  message = "recurringRecorded"
+
+	msgUsage            message = "usage"
+	msgBudgetNotInteger message = "budgetNotInteger"
+	msgInvalidCurrency  message = "invalidCurrency"
+	msgRateNotPositive  message = "rateNotPositive"
+	msgSetBudgetFirst   message = "setBudgetFirst"
+	msgBudgetSetFrom    message = "budgetSetFrom"
+	msgBudgetSetOnly    message = "budgetSetOnly"
+	msgInvalidMonth     message = "invalidMonth"
+
+	msgBudgetHistoryTitle message = "budgetHistoryTitle"
+	msgBudgetHistoryBase  message = "budgetHistoryBase"
+	msgBudgetHistoryFrom  message = "budgetHistoryFrom"
+	msgBudgetHistoryOnly  message = "budgetHistoryOnly"
+	msgRateSet            message = "rateSet"
+	msgInvalidLang        message = "invalidLang"
+	msgChannelLangSet     message = "channelLangSet"
+	msgUserLangSet        message = "userLangSet"
+	msgInvalidRollover    message = "invalidRollover"
+	msgRolloverSet        message = "rolloverSet"
+
+	msgPeriodUsage     message = "periodUsage"
+	msgPeriodAdded     message = "periodAdded"
+	msgPeriodRemoved   message = "periodRemoved"
+	msgPeriodNotFound  message = "periodNotFound"
+	msgPeriodEmpty     message = "periodEmpty"
+	msgPeriodListTitle message = "periodListTitle"
+	msgPeriodYearly    message = "periodYearly"
+
+	msgPermissionsUsage        message = "permissionsUsage"
+	msgPermissionsTitle        message = "permissionsTitle"
+	msgPermissionsOwners       message = "permissionsOwners"
+	msgPermissionsAdmins       message = "permissionsAdmins"
+	msgPermissionsSetBudget    message = "permissionsSetBudget"
+	msgPermissionsDeleteOthers message = "permissionsDeleteOthers"
+	msgPermissionsUpdated      message = "permissionsUpdated"
+	msgRoleOwners              message = "roleOwners"
+	msgRoleAdmins              message = "roleAdmins"
+	msgRoleAnyone              message = "roleAnyone"
+	msgNobody                  message = "nobody"
+	msgNotAllowed              message = "notAllowed"
+	msgCannotDeleteOthers      message = "cannotDeleteOthers"
+	msgBudgetChangedBy         message = "budgetChangedBy"
+	msgCannotEditOthers        message = "cannotEditOthers"
+	msgCannotCancelOthers      message = "cannotCancelOthers"
+	msgAlreadyRecorded         message = "alreadyRecorded"
+
+	msgAuditUsage              message = "auditUsage"
+	msgAuditEmpty              message = "auditEmpty"
+	msgAuditTitle              message = "auditTitle"
+	msgAuditSystem             message = "auditSystem"
+	msgAuditUnknownActor       message = "auditUnknownActor"
+	msgAuditChannelSaved       message = "auditChannelSaved"
+	msgAuditExpenditureAdded   message = "auditExpenditureAdded"
+	msgAuditExpenditureUpdated message = "auditExpenditureUpdated"
+	msgAuditExpenditureDeleted message = "auditExpenditureDeleted"
+
+	msgAuditExpenditureRestored message = "auditExpenditureRestored"
+	msgExpenditureRestored      message = "expenditureRestored"
+	msgCannotRestoreOthers      message = "cannotRestoreOthers"
+	msgTrashUsage               message = "trashUsage"
+	msgTrashEmpty               message = "trashEmpty"
+	msgTrashTitle               message = "trashTitle"
+	msgTrashItem                message = "trashItem"
+	msgTrashNotFound            message = "trashNotFound"
+	msgTrashRestored            message = "trashRestored"
+)
+
+var catalog = map[message]map[lang]string{
+	msgExpenditureAdded: {
+		langJA: "💸 カード利用を登録しました。",
+		langEN: "💸 Recorded a card payment.",
+	},
+	msgExpenditureDeleted: {
+		langJA: "🗑 カード利用を削除しました。",
+		langEN: "🗑 Deleted a card payment.",
+	},
+	msgFieldUsed: {
+		langJA: "利用額",
+		langEN: "Amount",
+	},
+	msgFieldDeleted: {
+		langJA: "削除額",
+		langEN: "Deleted amount",
+	},
+	msgFieldRemaining: {
+		langJA: "今月の利用可能残額",
+		langEN: "Remaining this month",
+	},
+	msgFieldTotal: {
+		langJA: "今月の合計利用額",
+		langEN: "Total this month",
+	},
+	msgFieldBudget: {
+		langJA: "今月の設定上限額",
+		langEN: "Budget this month",
+	},
+	msgThresholdCrossed: {
+		langJA: "⚠️ 今月の利用額が上限額の %d%% に達しました。",
+		langEN: "⚠️ Spending this month reached %d%% of the budget.",
+	},
+	msgExpenditureUpdated: {
+		langJA: "✏️ カード利用を修正しました。",
+		langEN: "✏️ Updated a card payment.",
+	},
+	msgBackdated: {
+		langJA: "📅 %s の利用として登録しました。",
+		langEN: "📅 Recorded as paid on %s.",
+	},
+	msgFieldRollover: {
+		langJA: "繰越",
+		langEN: "Carried over",
+	},
+	msgFieldYearToDate: {
+		langJA: "年初来",
+		langEN: "Year to date",
+	},
+	msgProcessFailed: {
+		langJA: "支出を処理できませんでした。しばらくしてからもう一度お試しください。",
+		langEN: "Failed to process the expenditure. Please try again later.",
+	},
+	msgUnknownRate: {
+		langJA: "%[1]s のレートが不明です。`/moneysaver rate %[1]s 150.2` のように設定してください。",
+		langEN: "Unknown exchange rate of %[1]s. Set it like `/moneysaver rate %[1]s 150.2`.",
+	},
+	msgActionUndo: {
+		langJA: "取り消す",
+		langEN: "Undo",
+	},
+	msgActionEdit: {
+		langJA: "金額を修正",
+		langEN: "Edit amount",
+	},
+	msgActionCategorize: {
+		langJA: "カテゴリー",
+		langEN: "Category",
+	},
+	msgEditAmountTitle: {
+		langJA: "金額の修正",
+		langEN: "Edit amount",
+	},
+	msgFieldAmount: {
+		langJA: "金額",
+		langEN: "Amount",
+	},
+	msgSave: {
+		langJA: "保存",
+		langEN: "Save",
+	},
+	msgCancel: {
+		langJA: "キャンセル",
+		langEN: "Cancel",
+	},
+	msgInvalidAmount: {
+		langJA: "金額は 1200 や USD 42.50 のように入力してください。",
+		langEN: "Enter an amount such as 1200 or USD 42.50.",
+	},
+	msgAddTitle: {
+		langJA: "カード利用の登録",
+		langEN: "Add a card payment",
+	},
+	msgAdd: {
+		langJA: "登録",
+		langEN: "Add",
+	},
+	msgFieldChannel: {
+		langJA: "チャンネル",
+		langEN: "Channel",
+	},
+	msgFieldCategory: {
+		langJA: "カテゴリー",
+		langEN: "Category",
+	},
+	msgFieldMemo: {
+		langJA: "メモ",
+		langEN: "Memo",
+	},
+	msgFieldDate: {
+		langJA: "日付",
+		langEN: "Date",
+	},
+	msgFieldPayer: {
+		langJA: "支払った人",
+		langEN: "Paid by",
+	},
+	msgFutureDate: {
+		langJA: "今日より後の日付は指定できません。",
+		langEN: "Date can't be after today.",
+	},
+	msgNoBudget: {
+		langJA: "このチャンネルには上限額が設定されていません。",
+		langEN: "This channel has no budget.",
+	},
+	msgHomeTitle: {
+		langJA: "💸 今月のカード利用",
+		langEN: "💸 Card payments this month",
+	},
+	msgHomeNoChannels: {
+		langJA: "参加しているチャンネルに上限額が設定されていません。チャンネルで `/moneysaver set 100000` を実行してください。",
+		langEN: "None of your channels has a budget. Run `/moneysaver set 100000` in a channel.",
+	},
+	msgHomeRemaining: {
+		langJA: "残り %s / 上限 %s",
+		langEN: "%s left of %s",
+	},
+	msgHomeEmpty: {
+		langJA: "今月の利用はまだありません。",
+		langEN: "No payments this month.",
+	},
+	msgHomeTopCategories: {
+		langJA: "カテゴリー",
+		langEN: "Top categories",
+	},
+	msgHomeRecent: {
+		langJA: "最近の利用",
+		langEN: "Recent payments",
+	},
+	msgUncategorized: {
+		langJA: "未分類",
+		langEN: "Uncategorized",
+	},
+	msgHomeRollover: {
+		langJA: "繰越 %s",
+		langEN: "%s carried over",
+	},
+	msgRecurringUsage: {
+		langJA: "使い方: `/moneysaver recurring add 1480 #subscriptions Netflix on 5`、`/moneysaver recurring list`、`/moneysaver recurring cancel ID`",
+		langEN: "Usage: `/moneysaver recurring add 1480 #subscriptions Netflix on 5`, `/moneysaver recurring list` or `/moneysaver recurring cancel ID`",
+	},
+	msgRecurringAdded: {
+		langJA: "🔁 「%s」を毎月 %d 日に登録します。ID: `%s`",
+		langEN: "🔁 %s will be recorded on day %d every month. ID: `%s`",
+	},
+	msgRecurringListTitle: {
+		langJA: "🔁 定期支出",
+		langEN: "🔁 Recurring payments",
+	},
+	msgRecurringItem: {
+		langJA: "`%s` %s %s 毎月 %d 日",
+		langEN: "`%s` %s %s on day %d",
+	},
+	msgRecurringEmpty: {
+		langJA: "定期支出は登録されていません。",
+		langEN: "No recurring payments.",
+	},
+	msgRecurringCanceled: {
+		langJA: "定期支出 `%s` を解除しました。",
+		langEN: "Canceled recurring payment `%s`.",
+	},
+	msgRecurringNotFound: {
+		langJA: "定期支出 `%s` は見つかりません。",
+		langEN: "Recurring payment `%s` is not found.",
+	},
+	msgRecurringRecorded: {
+		langJA: "🔁 定期支出「%s」を登録しました。",
+		langEN: "🔁 Recorded recurring payment %s.",
+	},
+	msgUsage: {
+		langJA: "コマンドの形式が正しくありません。使い方: `/moneysaver set 1000 [JPY] [--from 2026-12|--only 2026-12]`、`/moneysaver budgets`、`/moneysaver rate USD 150.2`、`/moneysaver lang en [me]`、`/moneysaver add`、`/moneysaver rollover both [50000]`、`/moneysaver period list`、`/moneysaver recurring list`、`/moneysaver permissions`、`/moneysaver audit`、`/moneysaver trash`",
+		langEN: "Invalid command format. Usage: `/moneysaver set 1000 [JPY] [--from 2026-12|--only 2026-12]`, `/moneysaver budgets`, `/moneysaver rate USD 150.2`, `/moneysaver lang en [me]`, `/moneysaver add`, `/moneysaver rollover both [50000]`, `/moneysaver period list`, `/moneysaver recurring list`, `/moneysaver permissions`, `/moneysaver audit` or `/moneysaver trash`",
+	},
+	msgBudgetNotInteger: {
+		langJA: "上限額は整数で指定してください。",
+		langEN: "Budget must be an integer.",
+	},
+	msgInvalidCurrency: {
+		langJA: "通貨は JPY のような ISO 4217 コードで指定してください。",
+		langEN: "Currency must be an ISO 4217 code such as JPY.",
+	},
+	msgRateNotPositive: {
+		langJA: "レートは正の数で指定してください。",
+		langEN: "Rate must be a positive number.",
+	},
+	msgSetBudgetFirst: {
+		langJA: "先に上限額を設定してください。使い方: `/moneysaver set 1000`",
+		langEN: "Set budget first. Usage: `/moneysaver set 1000`",
+	},
+	msgBudgetSetFrom: {
+		langJA: "#%s の %s 以降の上限額を設定しました。",
+		langEN: "Set budget of #%s from %s",
+	},
+	msgBudgetSetOnly: {
+		langJA: "#%s の %s のみの上限額を設定しました。",
+		langEN: "Set budget of #%s only in %s",
+	},
+	msgInvalidMonth: {
+		langJA: "月は `--from 2026-12` または `--only 2026-12` のように指定してください。",
+		langEN: "Specify a month like `--from 2026-12` or `--only 2026-12`.",
+	},
+	msgBudgetHistoryTitle: {
+		langJA: "📒 上限額の履歴",
+		langEN: "📒 Budget history",
+	},
+	msgBudgetHistoryBase: {
+		langJA: "初期 %s",
+		langEN: "Initially %s",
+	},
+	msgBudgetHistoryFrom: {
+		langJA: "%s 以降 %s",
+		langEN: "From %s: %s",
+	},
+	msgBudgetHistoryOnly: {
+		langJA: "%s のみ %s",
+		langEN: "Only %s: %s",
+	},
+	msgRateSet: {
+		langJA: "#%s のレートを設定しました: 1 %s = %v %s",
+		langEN: "Set rate to #%s: 1 %s = %v %s",
+	},
+	msgInvalidLang: {
+		langJA: "言語は ja または en で指定してください。",
+		langEN: "Language must be ja or en.",
+	},
+	msgChannelLangSet: {
+		langJA: "#%s の言語を %s に設定しました。",
+		langEN: "Set language of #%s to %s",
+	},
+	msgUserLangSet: {
+		langJA: "あなたの言語を %s に設定しました。",
+		langEN: "Set your language to %s",
+	},
+	msgInvalidRollover: {
+		langJA: "繰越は none、surplus、deficit、both のいずれかと 0 以上の上限額で指定してください。使い方: `/moneysaver rollover both [50000]`",
+		langEN: "Rollover must be none, surplus, deficit or both with an optional non-negative cap. Usage: `/moneysaver rollover both [50000]`",
+	},
+	msgRolloverSet: {
+		langJA: "#%s の繰越を %s に設定しました。",
+		langEN: "Set rollover of #%s to %s",
+	},
+	msgPeriodUsage: {
+		langJA: "使い方: `/moneysaver period add travel 300000 yearly [#travel]`、`/moneysaver period add summer 200000 2026-07..2026-09`（最長 24 か月）、`/moneysaver period list`、`/moneysaver period remove travel`",
+		langEN: "Usage: `/moneysaver period add travel 300000 yearly [#travel]`, `/moneysaver period add summer 200000 2026-07..2026-09` (up to 24 months), `/moneysaver period list` or `/moneysaver period remove travel`",
+	},
+	msgPeriodAdded: {
+		langJA: "📆 予算 %s を設定しました。",
+		langEN: "📆 Set budget %s",
+	},
+	msgPeriodRemoved: {
+		langJA: "予算 %s を削除しました。",
+		langEN: "Removed budget %s",
+	},
+	msgPeriodNotFound: {
+		langJA: "予算 %s は見つかりません。",
+		langEN: "Budget %s is not found.",
+	},
+	msgPeriodEmpty: {
+		langJA: "年間・期間の予算は設定されていません。",
+		langEN: "No yearly or period budgets.",
+	},
+	msgPeriodListTitle: {
+		langJA: "📆 年間・期間の予算",
+		langEN: "📆 Yearly and period budgets",
+	},
+	msgPeriodYearly: {
+		langJA: "毎年",
+		langEN: "yearly",
+	},
+	msgPermissionsUsage: {
+		langJA: "使い方: `/moneysaver permissions`、`/moneysaver permissions owner|admin add|remove @user`、`/moneysaver permissions budget|delete anyone|admins|owners`",
+		langEN: "Usage: `/moneysaver permissions`, `/moneysaver permissions owner|admin add|remove @user` or `/moneysaver permissions budget|delete anyone|admins|owners`",
+	},
+	msgPermissionsTitle: {
+		langJA: "🔐 このチャンネルの権限",
+		langEN: "🔐 Permissions of this channel",
+	},
+	msgPermissionsOwners: {
+		langJA: "オーナー: %s",
+		langEN: "Owners: %s",
+	},
+	msgPermissionsAdmins: {
+		langJA: "管理者: %s",
+		langEN: "Admins: %s",
+	},
+	msgPermissionsSetBudget: {
+		langJA: "予算の変更: %s",
+		langEN: "Changing budgets: %s",
+	},
+	msgPermissionsDeleteOthers: {
+		langJA: "他の人の支出の削除: %s",
+		langEN: "Deleting expenditures of others: %s",
+	},
+	msgPermissionsUpdated: {
+		langJA: "権限を変更しました。",
+		langEN: "Updated permissions.",
+	},
+	msgRoleOwners: {
+		langJA: "オーナー",
+		langEN: "owners",
+	},
+	msgRoleAdmins: {
+		langJA: "オーナーと管理者",
+		langEN: "owners and admins",
+	},
+	msgRoleAnyone: {
+		langJA: "全員",
+		langEN: "anyone",
+	},
+	msgNobody: {
+		langJA: "なし",
+		langEN: "none",
+	},
+	msgNotAllowed: {
+		langJA: "⛔ このチャンネルでこの操作ができるのは%sだけです。",
+		langEN: "⛔ Only %s can do this in this channel.",
+	},
+	msgCannotDeleteOthers: {
+		langJA: "⛔ このチャンネルで他の人の支出を削除できるのは%sだけです。",
+		langEN: "⛔ Only %s can delete expenditures of others in this channel.",
+	},
+	msgCannotEditOthers: {
+		langJA: "⛔ このチャンネルで他の人の支出を変更できるのは%sだけです。",
+		langEN: "⛔ Only %s can change expenditures of others in this channel.",
+	},
+	msgCannotCancelOthers: {
+		langJA: "⛔ このチャンネルで他の人の定期支出を解約できるのは%sだけです。",
+		langEN: "⛔ Only %s can cancel recurring expenditures of others in this channel.",
+	},
+	msgAlreadyRecorded: {
+		langJA: "このメッセージの支出はすでに登録されています。",
+		langEN: "The expenditure of this message is already recorded.",
+	},
+	msgBudgetChangedBy: {
+		langJA: "📝 <@%s> が予算を %s から %s に変更しました。",
+		langEN: "📝 <@%s> changed the budget from %s to %s.",
+	},
+	msgAuditUsage: {
+		langJA: "使い方: `/moneysaver audit [件数 (1〜50)]`",
+		langEN: "Usage: `/moneysaver audit [count (1-50)]`",
+	},
+	msgAuditEmpty: {
+		langJA: "このチャンネルの変更履歴はありません。",
+		langEN: "There is no change history in this channel.",
+	},
+	msgAuditTitle: {
+		langJA: "*変更履歴*",
+		langEN: "*Change history*",
+	},
+	msgAuditSystem: {
+		langJA: "MoneySaver",
+		langEN: "MoneySaver",
+	},
+	msgAuditUnknownActor: {
+		langJA: "不明なユーザー",
+		langEN: "Someone",
+	},
+	msgAuditChannelSaved: {
+		langJA: "%s がチャンネルの設定を変更しました (%s)",
+		langEN: "%s changed the channel settings (%s)",
+	},
+	msgAuditExpenditureAdded: {
+		langJA: "%s が %v の支出を登録しました (%s)",
+		langEN: "%s recorded an expenditure of %v (%s)",
+	},
+	msgAuditExpenditureUpdated: {
+		langJA: "%s が支出 %s を変更しました (%s)",
+		langEN: "%s updated the expenditure %s (%s)",
+	},
+	msgAuditExpenditureDeleted: {
+		langJA: "%s が %v の支出を削除しました (%s)",
+		langEN: "%s deleted an expenditure of %v (%s)",
+	},
+	msgAuditExpenditureRestored: {
+		langJA: "%s が %v の支出を元に戻しました (%s)",
+		langEN: "%s restored an expenditure of %v (%s)",
+	},
+	msgExpenditureRestored: {
+		langJA: "♻️ カード利用を元に戻しました。",
+		langEN: "♻️ Restored a card payment.",
+	},
+	msgCannotRestoreOthers: {
+		langJA: "⛔ このチャンネルで他の人の支出を元に戻せるのは%sだけです。",
+		langEN: "⛔ Only %s can restore expenditures of others in this channel.",
+	},
+	msgTrashUsage: {
+		langJA: "使い方: `/moneysaver trash`、`/moneysaver trash restore <キー>`",
+		langEN: "Usage: `/moneysaver trash` or `/moneysaver trash restore <key>`",
+	},
+	msgTrashEmpty: {
+		langJA: "ゴミ箱は空です。",
+		langEN: "The trash is empty.",
+	},
+	msgTrashTitle: {
+		langJA: "*ゴミ箱* (`/moneysaver trash restore <キー>` で元に戻せます)",
+		langEN: "*Trash* (restore with `/moneysaver trash restore <key>`)",
+	},
+	msgTrashItem: {
+		langJA: "`%s` %s %s (%s が %s に削除)",
+		langEN: "`%s` %s %s (deleted by %s on %s)",
+	},
+	msgTrashNotFound: {
+		langJA: "ゴミ箱に %s はありません。",
+		langEN: "%s is not in the trash.",
+	},
+	msgTrashRestored: {
+		langJA: "%s を元に戻しました。",
+		langEN: "Restored %s.",
+	},
+}
+
+// t renders the message in the language.
+func (l lang) t(m message, args ...interface{}) string {
+	texts, ok := catalog[m]
+	if !ok {
+		return string(m)
+	}
+
+	text, ok := texts[l]
+	if !ok {
+		text = texts[defaultLang]
+	}
+
+	if len(args) == 0 {
+		return text
+	}
+
+	return fmt.Sprintf(text, args...)
+}
+
+// langFor resolves the language with the precedence of user, channel and default.
+// r and ch may be nil.
+func langFor(ctx context.Context, r *userRepo, ch *channel, userID string) lang {
+	if r != nil && userID != "" {
+		u, err := r.findByID(ctx, userID)
+		if err == nil && u.Lang != "" {
+			return lang(u.Lang)
+		} else if err != nil && !errors.Is(err, errNotFound) {
+			logger.ErrorContext(ctx, "r.findByID", slog.Any("err", err))
+		}
+	}
+
+	if ch != nil && ch.Lang != "" {
+		return lang(ch.Lang)
+	}
+
+	return defaultLang
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	slackgo "github.com/slack-go/slack"
+)
+
+// Arguments to render messages with placeholders.
+var messageArgs = map[message][]interface{}{
+	msgThresholdCrossed:         {80},
+	msgBudgetSetFrom:            {"general", "2026-12"},
+	msgBudgetSetOnly:            {"general", "2026-12"},
+	msgBudgetHistoryBase:        {"¥100,000"},
+	msgBudgetHistoryFrom:        {"2026-12", "¥150,000"},
+	msgBudgetHistoryOnly:        {"2026-12", "¥150,000"},
+	msgRateSet:                  {"general", "USD", 150.2, "JPY"},
+	msgChannelLangSet:           {"general", langEN},
+	msgUserLangSet:              {langEN},
+	msgHomeRemaining:            {"¥1,000", "¥2,000"},
+	msgBackdated:                {"2026-09-30"},
+	msgUnknownRate:              {"USD"},
+	msgRolloverSet:              {"general", rolloverBoth},
+	msgHomeRollover:             {"¥1,000"},
+	msgPeriodAdded:              {"travel (yearly)"},
+	msgPeriodRemoved:            {"travel"},
+	msgPeriodNotFound:           {"travel"},
+	msgRecurringAdded:           {"Netflix", 5, "abc"},
+	msgRecurringItem:            {"abc", "Netflix", "¥1,480", 5},
+	msgRecurringCanceled:        {"abc"},
+	msgRecurringNotFound:        {"abc"},
+	msgRecurringRecorded:        {"Netflix"},
+	msgPermissionsOwners:        {"<@U1>"},
+	msgPermissionsAdmins:        {"<@U1>"},
+	msgPermissionsSetBudget:     {"owners"},
+	msgPermissionsDeleteOthers:  {"owners"},
+	msgNotAllowed:               {"owners"},
+	msgCannotDeleteOthers:       {"owners"},
+	msgCannotEditOthers:         {"owners"},
+	msgCannotCancelOthers:       {"owners"},
+	msgBudgetChangedBy:          {"U1", "¥1,000", "¥2,000"},
+	msgAuditChannelSaved:        {"<@U1>", "budget"},
+	msgAuditExpenditureAdded:    {"<@U1>", 1000, "2026-10/123.456"},
+	msgAuditExpenditureUpdated:  {"<@U1>", "2026-10/123.456", "amount"},
+	msgAuditExpenditureDeleted:  {"<@U1>", 1000, "2026-10/123.456"},
+	msgAuditExpenditureRestored: {"<@U1>", 1000, "2026-10/123.456"},
+	msgCannotRestoreOthers:      {"owners"},
+	msgTrashItem:                {"2026-10/123.456", "2026-10-01", "¥1,000", "<@U1>", "2026-10-02"},
+	msgTrashNotFound:            {"2026-10/123.456"},
+	msgTrashRestored:            {"2026-10/123.456"},
+}
+
+func Test_catalog(t *testing.T) {
+	t.Parallel()
+
+	for m, texts := range catalog {
+		for _, l := range langs {
+			m, l := m, l
+
+			t.Run(string(m)+"/"+string(l), func(t *testing.T) {
+				t.Parallel()
+
+				if _, ok := texts[l]; !ok {
+					t.Fatalf("%s is not translated into %s", m, l)
+				}
+
+				text := l.t(m, messageArgs[m]...)
+				if text == "" {
+					t.Errorf("%s in %s is empty", m, l)
+				}
+
+				if strings.Contains(text, "%!") || strings.Contains(text, "%s") || strings.Contains(text, "%v") {
+					t.Errorf("%s in %s is rendered incorrectly: %s", m, l, text)
+				}
+			})
+		}
+	}
+}
+
+func Test_lang_t(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		l lang
+		m message
+		e string
+	}{
+		"ja":       {l: langJA, m: msgFieldUsed, e: "利用額"},
+		"en":       {l: langEN, m: msgFieldUsed, e: "Amount"},
+		"fallback": {l: lang("fr"), m: msgFieldUsed, e: "利用額"},
+		"unknown":  {l: langEN, m: message("unknown"), e: "unknown"},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if a := c.l.t(c.m); a != c.e {
+				t.Errorf("expected %s, but %s", c.e, a)
+			}
+		})
+	}
+}
+
+func Test_commandProcessor_processLang(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fs := getFirestoreClient(t)
+	p := &commandProcessor{channelRepo: &channelRepo{fs}, userRepo: &userRepo{fs}}
+
+	defer flushStore(t)
+
+	c := slackgo.SlashCommand{ChannelID: "ch-lang", ChannelName: "general", UserID: "U1"}
+
+	msg, err := p.processLang(ctx, c, langEN, nil, []string{"ja"})
+	if err != nil || msg == nil || msg.Text != langEN.t(msgSetBudgetFirst) {
+		t.Errorf("channels without budget should be rejected: %v, %v", msg, err)
+	}
+
+	if _, err := p.channelRepo.findByID(ctx, c.ChannelID); !errors.Is(err, errNotFound) {
+		t.Errorf("channel should not be created: %v", err)
+	}
+
+	if err := p.channelRepo.save(ctx, &channel{ID: c.ChannelID, Budget: 10000}); err != nil {
+		t.Fatalf("p.channelRepo.save: %v", err)
+	}
+
+	ch, err := p.channelRepo.findByID(ctx, c.ChannelID)
+	if err != nil {
+		t.Fatalf("p.channelRepo.findByID: %v", err)
+	}
+
+	if _, err := p.processLang(ctx, c, langEN, ch, []string{"ja"}); err != nil {
+		t.Fatalf("p.processLang: %v", err)
+	}
+
+	if ch, err := p.channelRepo.findByID(ctx, c.ChannelID); err != nil || ch.Lang != string(langJA) || ch.Budget != 10000 {
+		t.Errorf("language of the channel should be set: %+v, %v", ch, err)
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/nownabe/moneysaver/slack"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const metricsNamespace = "moneysaver"
+
+var (
+	metricsRegistry = prometheus.NewRegistry()
+
+	httpRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests by route and status code.",
+	}, []string{"route", "code"})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route"})
+
+	eventsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "events_total",
+		Help:      "Number of processed Slack events by event type, subtype and error class.",
+	}, []string{"type", "subtype", "error_class"})
+
+	eventDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "event_duration_seconds",
+		Help:      "Latency of processing Slack events by event type and subtype.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"type", "subtype"})
+
+	expendituresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "expenditures_total",
+		Help:      "Number of recorded, updated or deleted expenditures.",
+	}, []string{"action"})
+
+	commandsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "commands_total",
+		Help:      "Number of processed slash commands by command name and error class.",
+	}, []string{"command", "error_class"})
+
+	commandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "command_duration_seconds",
+		Help:      "Latency of processing slash commands by command name.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"command"})
+
+	interactionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "interactions_total",
+		Help:      "Number of processed interactions by type, action or callback ID and error class.",
+	}, []string{"type", "name", "error_class"})
+
+	interactionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "interaction_duration_seconds",
+		Help:      "Latency of processing interactions by type, action or callback ID.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"type", "name"})
+
+	storageOperationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "storage_operations_total",
+		Help:      "Number of Firestore operations by operation and error class.",
+	}, []string{"operation", "error_class"})
+
+	storageOperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "storage_operation_duration_seconds",
+		Help:      "Latency of Firestore operations by operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	slackRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "slack_requests_total",
+		Help:      "Number of Slack Web API calls by method and error class.",
+	}, []string{"method", "error_class"})
+
+	slackRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "slack_request_duration_seconds",
+		Help:      "Latency of Slack Web API calls by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	slackVerificationFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "slack_verification_failures_total",
+		Help:      "Number of requests rejected by Slack signature verification by reason.",
+	}, []string{"reason"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestsTotal,
+		httpRequestDuration,
+		eventsTotal,
+		eventDuration,
+		expendituresTotal,
+		commandsTotal,
+		commandDuration,
+		interactionsTotal,
+		interactionDuration,
+		storageOperationsTotal,
+		storageOperationDuration,
+		slackRequestsTotal,
+		slackRequestDuration,
+		slackVerificationFailuresTotal,
+	)
+}
+
+func newMetricsHandler() http.Handler {
+	r := chi.NewRouter()
+	r.Handle("/metrics", promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{}))
+
+	return r
+}
+
+// errorClass classifies errors into a small set of label values.
+func errorClass(err error) string {
+	var (
+		apiErr  *slack.APIError
+		httpErr httpError
+	)
+
+	switch {
+	case err == nil:
+		return "none"
+	case errors.Is(err, errNotFound):
+		return "not_found"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, slack.ErrRateLimited):
+		return "rate_limited"
+	case errors.As(err, &apiErr):
+		return "slack_api"
+	case errors.As(err, &httpErr):
+		return "http_" + strconv.Itoa(httpErr.Status())
+	}
+
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		s, ok := status.FromError(e)
+		if !ok {
+			continue
+		}
+
+		switch s.Code() {
+		case codes.NotFound:
+			return "not_found"
+		case codes.Unavailable:
+			return "unavailable"
+		case codes.DeadlineExceeded:
+			return "timeout"
+		}
+	}
+
+	return "internal"
+}
+
+// metricsMiddleware records requests by chi route pattern to keep label cardinality low.
+func metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		route := "unknown"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+
+		code := ww.Status()
+		if code == 0 {
+			code = http.StatusOK
+		}
+
+		httpRequestsTotal.WithLabelValues(route, strconv.Itoa(code)).Inc()
+		httpRequestDuration.WithLabelValues(route).Observe(time.Since(start).Seconds())
+	})
+}
+
+func observeEvent(eventType, subtype string, start time.Time, err *error) {
+	eventsTotal.WithLabelValues(eventType, subtype, errorClass(*err)).Inc()
+	eventDuration.WithLabelValues(eventType, subtype).Observe(time.Since(start).Seconds())
+}
+
+func observeCommand(command string, start time.Time, err *error) {
+	commandsTotal.WithLabelValues(command, errorClass(*err)).Inc()
+	commandDuration.WithLabelValues(command).Observe(time.Since(start).Seconds())
+}
+
+func observeInteraction(interactionType, name string, start time.Time, err *error) {
+	interactionsTotal.WithLabelValues(interactionType, name, errorClass(*err)).Inc()
+	interactionDuration.WithLabelValues(interactionType, name).Observe(time.Since(start).Seconds())
+}
+
+func observeStorage(operation string, start time.Time, err *error) {
+	storageOperationsTotal.WithLabelValues(operation, errorClass(*err)).Inc()
+	storageOperationDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+func observeSlack(method string, d time.Duration, err error) {
+	slackRequestsTotal.WithLabelValues(method, errorClass(err)).Inc()
+	slackRequestDuration.WithLabelValues(method).Observe(d.Seconds())
+}
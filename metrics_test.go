@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nownabe/moneysaver/slack"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_errorClass(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		err error
+		e   string
+	}{
+		"nil":         {err: nil, e: "none"},
+		"not found":   {err: fmt.Errorf("findByID: %w", errNotFound), e: "not_found"},
+		"canceled":    {err: fmt.Errorf("add: %w", context.Canceled), e: "canceled"},
+		"slack":       {err: fmt.Errorf("post: %w", &slack.APIError{Method: "chat.postMessage", Code: "channel_not_found"}), e: "slack_api"},
+		"ratelimited": {err: &slack.StatusError{Method: "chat.postMessage", StatusCode: http.StatusTooManyRequests}, e: "rate_limited"},
+		"http":        {err: wrap(http.StatusInternalServerError, "process: %w", context.Canceled), e: "canceled"},
+		"http status": {err: e(http.StatusBadRequest, "bad request"), e: "http_400"},
+		"grpc":        {err: fmt.Errorf("docRef.Set: %w", status.Error(codes.Unavailable, "down")), e: "unavailable"},
+		"other":       {err: fmt.Errorf("unknown"), e: "internal"},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if a := errorClass(c.err); a != c.e {
+				t.Errorf("expected %s, but %s", c.e, a)
+			}
+		})
+	}
+}
+
+func Test_metricsMiddleware(t *testing.T) {
+	t.Parallel()
+
+	r := newRouter(&handler{}, &healthHandler{}, newVerifier("secret"))
+
+	before := testutil.ToFloat64(httpRequestsTotal.WithLabelValues("/healthz", "200"))
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if a := testutil.ToFloat64(httpRequestsTotal.WithLabelValues("/healthz", "200")); a != before+1 {
+		t.Errorf("http_requests_total should be incremented: %v -> %v", before, a)
+	}
+
+	rec := httptest.NewRecorder()
+	newMetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if !strings.Contains(rec.Body.String(), "moneysaver_http_requests_total") {
+		t.Errorf("metrics should be exposed: %s", rec.Body.String())
+	}
+}
@@ -2,49 +2,126 @@ package main
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"io/ioutil"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"time"
+)
 
-	"github.com/slack-go/slack"
+const (
+	defaultSignatureTolerance = 5 * time.Minute
+	defaultMaxBodyBytes       = 1 << 20
 )
 
-func slackVerifier(signingSecret string) func(next http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			body, err := ioutil.ReadAll(r.Body)
-			if err != nil {
-				logger.Printf("ioutil.ReadAll: %v", err)
-				w.WriteHeader(http.StatusBadRequest)
+// Reasons of verification failures
+const (
+	verifyBodyTooLarge     = "body_too_large"
+	verifyMissingHeaders   = "missing_headers"
+	verifyStaleTimestamp   = "stale_timestamp"
+	verifyInvalidSignature = "invalid_signature"
+)
 
-				return
-			}
+// verifier verifies signatures of requests from Slack.
+// https://api.slack.com/authentication/verifying-requests-from-slack
+type verifier struct {
+	// Current secret first, and previous ones while rotating it
+	secrets []string
+	// Requests signed longer ago or later than this are rejected as replays.
+	tolerance time.Duration
+	// Larger bodies are rejected before being read.
+	maxBodyBytes int64
+	now          func() time.Time
+}
 
-			if err := r.Body.Close(); err != nil {
-				logger.Printf("r.Body.Close: %v", err)
-				w.WriteHeader(http.StatusInternalServerError)
+func newVerifier(secrets ...string) *verifier {
+	return &verifier{
+		secrets:      secrets,
+		tolerance:    defaultSignatureTolerance,
+		maxBodyBytes: defaultMaxBodyBytes,
+		now:          time.Now,
+	}
+}
 
-				return
+// verify returns the reason if the request isn't signed by any of the secrets in the tolerance.
+func (v *verifier) verify(h http.Header, body []byte) string {
+	ts, sig := h.Get("X-Slack-Request-Timestamp"), h.Get("X-Slack-Signature")
+	if ts == "" || sig == "" {
+		return verifyMissingHeaders
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return verifyStaleTimestamp
+	}
+
+	if d := v.now().Sub(time.Unix(unix, 0)); d > v.tolerance || d < -v.tolerance {
+		return verifyStaleTimestamp
+	}
+
+	for i, secret := range v.secrets {
+		if hmac.Equal([]byte(sig), []byte(signature(secret, ts, body))) {
+			if i > 0 {
+				logger.Debug("request is signed by a previous secret", slog.Int("index", i))
 			}
 
-			r.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+			return ""
+		}
+	}
+
+	return verifyInvalidSignature
+}
+
+func signature(secret, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + ts + ":"))
+	mac.Write(body)
+
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
 
-			verifier, err := slack.NewSecretsVerifier(r.Header, signingSecret)
+func slackVerifier(v *verifier) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, v.maxBodyBytes))
 			if err != nil {
-				logger.Printf("slack.NewSecretsVerifier: %v", err)
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					slackVerificationFailuresTotal.WithLabelValues(verifyBodyTooLarge).Inc()
+					logger.WarnContext(r.Context(), "request body is too large", slog.Int64("limit", maxBytesErr.Limit))
+					w.WriteHeader(http.StatusRequestEntityTooLarge)
+
+					return
+				}
+
+				logger.ErrorContext(r.Context(), "ioutil.ReadAll", slog.Any("err", err))
 				w.WriteHeader(http.StatusBadRequest)
 
 				return
 			}
 
-			if _, err := verifier.Write(body); err != nil {
-				logger.Printf("verifier.Write: %v", err)
+			if err := r.Body.Close(); err != nil {
+				logger.ErrorContext(r.Context(), "r.Body.Close", slog.Any("err", err))
 				w.WriteHeader(http.StatusInternalServerError)
 
 				return
 			}
 
-			if err := verifier.Ensure(); err != nil {
-				w.WriteHeader(http.StatusUnauthorized)
+			r.Body = ioutil.NopCloser(bytes.NewBuffer(body))
+
+			if reason := v.verify(r.Header, body); reason != "" {
+				slackVerificationFailuresTotal.WithLabelValues(reason).Inc()
+				logger.WarnContext(r.Context(), "failed to verify request", slog.String("reason", reason))
+
+				if reason == verifyMissingHeaders {
+					w.WriteHeader(http.StatusBadRequest)
+				} else {
+					w.WriteHeader(http.StatusUnauthorized)
+				}
 
 				return
 			}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func Test_slackVerifier(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	v := newVerifier("current", "previous")
+	v.tolerance = time.Minute
+	v.maxBodyBytes = 16
+	v.now = func() time.Time { return now }
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	h := slackVerifier(v)(next)
+
+	cases := map[string]struct {
+		secret string
+		at     time.Time
+		body   string
+		code   int
+		reason string
+	}{
+		"current secret":  {secret: "current", at: now, body: "token=x", code: http.StatusOK},
+		"previous secret": {secret: "previous", at: now.Add(-time.Minute), body: "token=x", code: http.StatusOK},
+		"unknown secret":  {secret: "unknown", at: now, body: "token=x", code: http.StatusUnauthorized, reason: verifyInvalidSignature},
+		"stale":           {secret: "current", at: now.Add(-time.Minute - time.Second), body: "token=x", code: http.StatusUnauthorized, reason: verifyStaleTimestamp},
+		"future":          {secret: "current", at: now.Add(time.Minute + time.Second), body: "token=x", code: http.StatusUnauthorized, reason: verifyStaleTimestamp},
+		"unsigned":        {body: "token=x", code: http.StatusBadRequest, reason: verifyMissingHeaders},
+		"too large":       {secret: "current", at: now, body: strings.Repeat("x", 17), code: http.StatusRequestEntityTooLarge, reason: verifyBodyTooLarge},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/commands", strings.NewReader(c.body))
+
+			if c.secret != "" {
+				ts := strconv.FormatInt(c.at.Unix(), 10)
+				req.Header.Set("X-Slack-Request-Timestamp", ts)
+				req.Header.Set("X-Slack-Signature", signature(c.secret, ts, []byte(c.body)))
+			}
+
+			var before float64
+			if c.reason != "" {
+				before = testutil.ToFloat64(slackVerificationFailuresTotal.WithLabelValues(c.reason))
+			}
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != c.code {
+				t.Errorf("status code should be %d, but %d", c.code, rec.Code)
+			}
+
+			if c.reason == "" {
+				return
+			}
+
+			if a := testutil.ToFloat64(slackVerificationFailuresTotal.WithLabelValues(c.reason)); a != before+1 {
+				t.Errorf("failures of %s should be incremented: %v -> %v", c.reason, before, a)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// migrateTeam copies documents of a single workspace at the root under the team, so that they're kept
+// after switching to multiple workspaces. It returns how many documents are copied.
+// Documents which already exist under the team are not overwritten, so it can be run again.
+// Documents at the root are kept.
+func migrateTeam(ctx context.Context, c *firestore.Client, teamID string) (n int, err error) {
+	ctx, done := instrumentStorage(ctx, "teams.migrate")
+	defer done(&err)
+
+	for _, name := range []string{collectionName, userCollectionName} {
+		m := &teamMigration{
+			Client:  c,
+			src:     c.Collection(name),
+			dstPath: teamCollectionName + "/" + teamID + "/" + name,
+		}
+
+		copied, err := m.copyCollection(ctx, m.src, m.Collection(m.dstPath))
+		n += copied
+
+		if err != nil {
+			return n, fmt.Errorf("m.copyCollection(%s): %w", name, err)
+		}
+	}
+
+	return n, nil
+}
+
+// teamMigration copies a root collection with its subcollections.
+type teamMigration struct {
+	*firestore.Client
+	src *firestore.CollectionRef
+	// Path of the collection under the team, relative to the database
+	dstPath string
+}
+
+func (m *teamMigration) copyCollection(ctx context.Context, src, dst *firestore.CollectionRef) (int, error) {
+	n := 0
+
+	// Missing documents are included, since they may have subcollections.
+	refs, err := src.DocumentRefs(ctx).GetAll()
+	if err != nil {
+		return n, fmt.Errorf("src.DocumentRefs.GetAll: %w", err)
+	}
+
+	for _, ref := range refs {
+		copied, err := m.copyDocument(ctx, ref, dst.Doc(ref.ID))
+		n += copied
+
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+func (m *teamMigration) copyDocument(ctx context.Context, src, dst *firestore.DocumentRef) (int, error) {
+	n := 0
+
+	doc, err := src.Get(ctx)
+	if err != nil && status.Code(err) != codes.NotFound {
+		return n, fmt.Errorf("src.Get(%s): %w", src.Path, err)
+	}
+
+	if doc.Exists() {
+		_, err := dst.Create(ctx, m.rebase(doc.Data()))
+		if err == nil {
+			n++
+		} else if status.Code(err) != codes.AlreadyExists {
+			return n, fmt.Errorf("dst.Create(%s): %w", dst.Path, err)
+		}
+	}
+
+	cols, err := src.Collections(ctx).GetAll()
+	if err != nil {
+		return n, fmt.Errorf("src.Collections.GetAll: %w", err)
+	}
+
+	for _, col := range cols {
+		copied, err := m.copyCollection(ctx, col, dst.Collection(col.ID))
+		n += copied
+
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// rebase points references into the root collection, e.g. of trash entries, to the copies under the team.
+func (m *teamMigration) rebase(data map[string]interface{}) map[string]interface{} {
+	for k, v := range data {
+		ref, ok := v.(*firestore.DocumentRef)
+		if !ok || !strings.HasPrefix(ref.Path, m.src.Path+"/") {
+			continue
+		}
+
+		data[k] = m.Doc(m.dstPath + "/" + strings.TrimPrefix(ref.Path, m.src.Path+"/"))
+	}
+
+	return data
+}
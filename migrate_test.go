@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func Test_migrateTeam(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fs := getFirestoreClient(t)
+	tctx := withTeam(ctx, "T0001", "")
+
+	defer flushStore(t)
+
+	chRepo := &channelRepo{fs}
+	exRepo := &expenditureRepo{fs}
+
+	if err := chRepo.save(ctx, &channel{ID: "ch1", Budget: 10000}); err != nil {
+		t.Fatalf("chRepo.save: %v", err)
+	}
+
+	if err := (&userRepo{fs}).save(ctx, &user{ID: "U1", Lang: string(langEN)}); err != nil {
+		t.Fatalf("userRepo.save: %v", err)
+	}
+
+	kept := &expenditure{Channel: "ch1", TS: "1790000000.000100", Amount: 1200, Timestamp: time.Unix(1790000000, 0)}
+	deleted := &expenditure{Channel: "ch1", TS: "1790000000.000200", Amount: 300, Timestamp: time.Unix(1790000000, 0)}
+
+	for _, ex := range []*expenditure{kept, deleted} {
+		if err := exRepo.add(ctx, ex); err != nil {
+			t.Fatalf("exRepo.add: %v", err)
+		}
+	}
+
+	if err := exRepo.delete(ctx, deleted); err != nil {
+		t.Fatalf("exRepo.delete: %v", err)
+	}
+
+	// Saved after switching, so it shouldn't be overwritten.
+	if err := chRepo.save(tctx, &channel{ID: "ch1", Budget: 20000}); err != nil {
+		t.Fatalf("chRepo.save: %v", err)
+	}
+
+	if _, err := migrateTeam(ctx, fs, "T0001"); err != nil {
+		t.Fatalf("migrateTeam: %v", err)
+	}
+
+	n, err := migrateTeam(ctx, fs, "T0001")
+	if err != nil || n != 0 {
+		t.Errorf("migrating again should copy nothing: %d, %v", n, err)
+	}
+
+	if ch, err := chRepo.findByID(tctx, "ch1"); err != nil || ch.Budget != 20000 {
+		t.Errorf("channels under the team should be kept: %v, %v", ch, err)
+	}
+
+	if u, err := (&userRepo{fs}).findByID(tctx, "U1"); err != nil || u.Lang != string(langEN) {
+		t.Errorf("users should be copied: %v, %v", u, err)
+	}
+
+	if ex, err := exRepo.findByKey(tctx, "ch1", kept.key()); err != nil || ex.Amount != 1200 {
+		t.Errorf("expenditures should be copied: %v, %v", ex, err)
+	}
+
+	if exs, err := exRepo.listDeleted(tctx, "ch1", maxTrashEntries); err != nil || len(exs) != 1 || exs[0].Amount != 300 {
+		t.Errorf("trash should point to the copies: %v, %v", exs, err)
+	}
+
+	if _, err := chRepo.findByID(ctx, "ch1"); err != nil {
+		t.Errorf("documents at the root should be kept: %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/slack-go/slack/slackevents"
@@ -12,39 +13,180 @@ var errNotExpenditureMessage = fmt.Errorf("not expenditure message")
 
 // https://firebase.google.com/docs/firestore/manage-data/data-types?hl=ja#data_types
 type channel struct {
-	ID     string `firestore:"-"`
-	Budget int64  `firestore:"budget"`
+	ID string `firestore:"-"`
+	// Monthly budget in months before Budgets
+	Budget int64 `firestore:"budget"`
+	// History of budgets. Later ones take precedence.
+	Budgets []budgetVersion `firestore:"budgets,omitempty"`
+	// Budgets of years or custom periods alongside the monthly one
+	Periods []periodBudget `firestore:"periods,omitempty"`
+	// ISO 4217 currency code of the budget. Empty means JPY.
+	Currency string `firestore:"currency,omitempty"`
+	// Exchange rates of other currencies in the budget currency.
+	Rates map[string]float64 `firestore:"rates,omitempty"`
+	// Language of replies. Empty means the default language.
+	Lang string `firestore:"lang,omitempty"`
+	// What to carry over from the previous month. Empty means none.
+	Rollover rolloverMode `firestore:"rollover,omitempty"`
+	// Limit of the amount carried over. Zero means unlimited.
+	RolloverCap int64 `firestore:"rolloverCap,omitempty"`
+	// Users who manage permissions. Anyone can while it's empty.
+	Owners []string `firestore:"owners,omitempty"`
+	// Users who can do what's limited to admins in addition to owners
+	Admins []string `firestore:"admins,omitempty"`
+	// Who can change budgets. Empty means anyone.
+	CanSetBudget role `firestore:"canSetBudget,omitempty"`
+	// Who can delete expenditures recorded by others. Empty means anyone.
+	CanDeleteOthers role `firestore:"canDeleteOthers,omitempty"`
+}
+
+func (ch *channel) currency() string {
+	if ch.Currency == "" {
+		return defaultCurrency
+	}
+
+	return ch.Currency
+}
+
+// user is a Slack user's preferences.
+type user struct {
+	ID   string `firestore:"-"`
+	Lang string `firestore:"lang,omitempty"`
 }
 
 type expenditure struct {
 	Channel string `firestore:"-"`
 	// Slack timestamp which is used to identify the message
-	TS        string    `firestore:"-"`
-	Amount    int64     `firestore:"amount"`
+	TS string `firestore:"-"`
+	// Amount in the budget currency of the channel
+	Amount int64 `firestore:"amount"`
+	// When it was posted
 	Timestamp time.Time `firestore:"timestamp"`
+	// When it was paid, which decides the month. Zero in documents before backdating was supported.
+	EffectiveDate time.Time `firestore:"effectiveDate"`
+	// Amount and currency as posted. Currency is empty when posted without currency.
+	OriginalAmount float64 `firestore:"originalAmount,omitempty"`
+	Currency       string  `firestore:"currency,omitempty"`
+	// Empty means uncategorized.
+	Category string `firestore:"category,omitempty"`
+	Memo     string `firestore:"memo,omitempty"`
+	// Slack user ID of who paid. Empty means the poster.
+	Payer string `firestore:"payer,omitempty"`
+	// Slack user ID of who recorded it. Empty in documents before permissions were supported.
+	User string `firestore:"user,omitempty"`
+	// Recorded without a message such as with the modal, so TS is not of a message.
+	Manual bool `firestore:"manual,omitempty"`
+	// ID of the recurring item which recorded it
+	Recurring string `firestore:"recurring,omitempty"`
+	// Set when it's moved to the trash, which excludes it from totals until it's restored or purged.
+	DeletedAt time.Time `firestore:"deletedAt,omitempty"`
+	DeletedBy string    `firestore:"deletedBy,omitempty"`
 }
 
-func newExpenditure(ev *slackevents.MessageEvent) (*expenditure, error) {
-	a, err := strconv.ParseInt(ev.Text, 10, 64)
-	if err != nil {
-		return nil, errNotExpenditureMessage
+// newTS returns a unique ID in the format of Slack timestamps for expenditures without messages.
+func newTS(t time.Time) string {
+	return fmt.Sprintf("%d.%06d", t.Unix(), t.Nanosecond()/int(time.Microsecond))
+}
+
+// setAmount sets the amount as posted. Amounts in other currencies are converted later.
+func (ex *expenditure) setAmount(a amount) {
+	if a.currency == "" {
+		ex.Amount = a.plain
+		ex.OriginalAmount = 0
+		ex.Currency = ""
+	} else {
+		ex.OriginalAmount = a.original
+		ex.Currency = a.currency
+	}
+}
+
+// deleted reports whether it's in the trash.
+func (ex *expenditure) deleted() bool {
+	return !ex.DeletedAt.IsZero()
+}
+
+// effective returns when it was paid.
+func (ex *expenditure) effective() time.Time {
+	if ex.EffectiveDate.IsZero() {
+		return ex.Timestamp
 	}
 
+	return ex.EffectiveDate
+}
+
+// backdated reports whether it was paid before the day it was posted.
+func (ex *expenditure) backdated() bool {
+	return ex.effective().Format(dateLayout) != ex.Timestamp.Format(dateLayout)
+}
+
+// in converts the times into the location, which decides the month.
+func (ex *expenditure) in(loc *time.Location) {
+	ex.Timestamp = ex.Timestamp.In(loc)
+
+	if !ex.EffectiveDate.IsZero() {
+		ex.EffectiveDate = ex.EffectiveDate.In(loc)
+	}
+}
+
+// key identifies the expenditure in the channel, which is used as a value of buttons.
+func (ex *expenditure) key() string {
+	return ex.effective().Format(monthLayout) + "/" + ex.TS
+}
+
+// parseExpenditureKey returns the month and the Slack timestamp of the key.
+func parseExpenditureKey(key string) (string, string, error) {
+	month, ts, ok := strings.Cut(key, "/")
+	if !ok || month == "" || ts == "" {
+		return "", "", fmt.Errorf("invalid expenditure key: %q", key)
+	}
+
+	if _, err := time.Parse(monthLayout, month); err != nil {
+		return "", "", fmt.Errorf("invalid expenditure key: %q", key)
+	}
+
+	return month, ts, nil
+}
+
+// foreign reports whether the expenditure was posted in other currency than base.
+func (ex *expenditure) foreign(base string) bool {
+	return ex.Currency != "" && ex.Currency != base
+}
+
+// newExpenditure parses the message in the timezone of the channel.
+// Messages may start with a date like `9/30 1200` to backdate them.
+func newExpenditure(ev *slackevents.MessageEvent, loc *time.Location) (*expenditure, error) {
 	ut, err := strconv.ParseFloat(ev.TimeStamp, 64)
 	if err != nil {
 		return nil, fmt.Errorf("strconv.ParseFloat: %w", err)
 	}
 
-	return &expenditure{
-		Channel:   ev.Channel,
-		TS:        ev.TimeStamp,
-		Amount:    a,
-		Timestamp: time.Unix(int64(ut), 0),
-	}, nil
+	posted := time.Unix(int64(ut), 0).In(loc)
+
+	text, date := ev.Text, posted
+	if d, rest, ok := parseDatePrefix(text, posted); ok {
+		text, date = rest, d
+	}
+
+	a, err := parseAmount(text)
+	if err != nil {
+		return nil, err
+	}
+
+	ex := &expenditure{
+		Channel:       ev.Channel,
+		TS:            ev.TimeStamp,
+		Timestamp:     posted,
+		EffectiveDate: date,
+		User:          ev.User,
+	}
+
+	ex.setAmount(a)
+
+	return ex, nil
 }
 
-func newExpenditureFromPreviousMessage(ev *slackevents.MessageEvent) (*expenditure, error) {
-	ex, err := newExpenditure(ev.PreviousMessage)
+func newExpenditureFromPreviousMessage(ev *slackevents.MessageEvent, loc *time.Location) (*expenditure, error) {
+	ex, err := newExpenditure(ev.PreviousMessage, loc)
 	if err != nil {
 		return nil, fmt.Errorf("newExpenditure: %w", err)
 	}
@@ -53,3 +195,137 @@ func newExpenditureFromPreviousMessage(ev *slackevents.MessageEvent) (*expenditu
 
 	return ex, nil
 }
+
+// recurring is an expenditure paid every month such as rent and subscriptions.
+type recurring struct {
+	ID      string `firestore:"-"`
+	Channel string `firestore:"-"`
+	// Workspace of the channel. Empty for a single workspace.
+	Team string `firestore:"-"`
+	// Organization of the workspace, which may have the installation
+	Enterprise string `firestore:"enterprise,omitempty"`
+	// Amount and currency as registered. Currency is empty when registered without currency.
+	Amount   float64 `firestore:"amount"`
+	Currency string  `firestore:"currency,omitempty"`
+	Category string  `firestore:"category,omitempty"`
+	Name     string  `firestore:"name"`
+	// Day of month when it's paid. It falls on the last day in shorter months.
+	Day int `firestore:"day"`
+	// First month to be recorded
+	StartMonth string `firestore:"startMonth"`
+	// Last month recorded. Empty means none.
+	LastMonth string    `firestore:"lastMonth,omitempty"`
+	CreatedBy string    `firestore:"createdBy"`
+	CreatedAt time.Time `firestore:"createdAt"`
+}
+
+// amount returns the amount to be recorded.
+func (rc *recurring) amount() amount {
+	if rc.Currency == "" {
+		return amount{plain: int64(rc.Amount)}
+	}
+
+	return amount{original: rc.Amount, currency: rc.Currency}
+}
+
+// dueDate returns when it's paid in the month.
+func (rc *recurring) dueDate(month string, loc *time.Location) (time.Time, error) {
+	m, err := time.ParseInLocation(monthLayout, month, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("time.ParseInLocation: %w", err)
+	}
+
+	last := m.AddDate(0, 1, -1).Day()
+
+	day := rc.Day
+	if day > last {
+		day = last
+	}
+
+	return m.AddDate(0, 0, day-1), nil
+}
+
+// dueMonths returns months which are due by now and not recorded yet.
+// Months missed while the scheduler was stopped are caught up.
+func (rc *recurring) dueMonths(now time.Time, loc *time.Location) []string {
+	month := rc.StartMonth
+	if rc.LastMonth != "" {
+		month = nextMonth(rc.LastMonth)
+	}
+
+	var months []string
+
+	for month != "" {
+		due, err := rc.dueDate(month, loc)
+		if err != nil || due.After(now) {
+			break
+		}
+
+		months = append(months, month)
+		month = nextMonth(month)
+	}
+
+	return months
+}
+
+// expenditure returns the expenditure of the month, whose Slack timestamp is fixed to record it only once.
+func (rc *recurring) expenditure(month string, now time.Time, loc *time.Location) (*expenditure, error) {
+	due, err := rc.dueDate(month, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	ex := &expenditure{
+		Channel:       rc.Channel,
+		TS:            "recurring-" + rc.ID + "-" + month,
+		Timestamp:     now.In(loc),
+		EffectiveDate: due,
+		Category:      rc.Category,
+		Memo:          rc.Name,
+		User:          rc.CreatedBy,
+		Manual:        true,
+		Recurring:     rc.ID,
+	}
+
+	ex.setAmount(rc.amount())
+
+	return ex, nil
+}
+
+// startMonth returns the first month to be recorded for items registered at now.
+// Items already due this month start from the next month.
+func startMonth(day int, now time.Time) string {
+	month := now.Format(monthLayout)
+
+	rc := &recurring{Day: day}
+	if due, err := rc.dueDate(month, now.Location()); err == nil && due.Format(dateLayout) < now.Format(dateLayout) {
+		return nextMonth(month)
+	}
+
+	return month
+}
+
+// nextMonth returns the month after the month. It returns empty for invalid months.
+func nextMonth(month string) string {
+	m, err := time.Parse(monthLayout, month)
+	if err != nil {
+		return ""
+	}
+
+	return m.AddDate(0, 1, 0).Format(monthLayout)
+}
+
+// installation is the app installed to a workspace, or to an Enterprise Grid organization.
+type installation struct {
+	// Team ID, or enterprise ID of organization-wide installations
+	ID           string `firestore:"-"`
+	TeamName     string `firestore:"teamName,omitempty"`
+	EnterpriseID string `firestore:"enterpriseId,omitempty"`
+	// Stored only by old versions without keys. `moneysaver reencrypt` seals it into SealedBotToken.
+	BotToken       string        `firestore:"botToken,omitempty"`
+	SealedBotToken *sealedSecret `firestore:"sealedBotToken,omitempty"`
+	BotUserID      string        `firestore:"botUserId"`
+	Scope          string        `firestore:"scope"`
+	InstalledBy    string        `firestore:"installedBy"`
+	InstalledAt    time.Time     `firestore:"installedAt"`
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nownabe/moneysaver/slack"
+)
+
+const (
+	slackAuthorizeURL = "https://slack.com/oauth/v2/authorize"
+
+	oauthStateCookie = "moneysaver_oauth_state"
+	oauthStateTTL    = 10 * time.Minute
+)
+
+// Bot scopes requested on installation
+var botScopes = []string{
+	"channels:history",
+	"channels:read",
+	"chat:write",
+	"commands",
+	"groups:history",
+	"groups:read",
+}
+
+var installedPage = template.Must(template.New("installed").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>MoneySaver</title></head>
+<body><p>MoneySaver has been installed to {{.}}. Invite it to your budget channels.</p></body></html>
+`))
+
+// oauthHandler serves the OAuth v2 flow which installs the app to workspaces.
+// State is signed with the client secret and bound to the browser by a cookie.
+type oauthHandler struct {
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	repo         *installationRepo
+	clients      *teamClients
+	slackOptions []slack.Option
+	now          func() time.Time
+}
+
+// handleInstall redirects to Slack to authorize the app.
+func (h *oauthHandler) handleInstall(w http.ResponseWriter, r *http.Request) {
+	nonce, err := newNonce()
+	if err != nil {
+		logger.ErrorContext(r.Context(), "newNonce", slog.Any("err", err))
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    nonce,
+		Path:     "/slack",
+		MaxAge:   int(oauthStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	q := url.Values{
+		"client_id": {h.clientID},
+		"scope":     {strings.Join(botScopes, ",")},
+		"state":     {h.state(nonce, h.now())},
+	}
+
+	if h.redirectURL != "" {
+		q.Set("redirect_uri", h.redirectURL)
+	}
+
+	http.Redirect(w, r, slackAuthorizeURL+"?"+q.Encode(), http.StatusFound)
+}
+
+// handleRedirect exchanges the code for a bot token and stores it as the installation.
+func (h *oauthHandler) handleRedirect(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	q := r.URL.Query()
+
+	if e := q.Get("error"); e != "" {
+		logger.WarnContext(ctx, "installation is canceled", slog.String("error", e))
+		http.Error(w, "Installation is canceled.", http.StatusForbidden)
+
+		return
+	}
+
+	cookie, err := r.Cookie(oauthStateCookie)
+	if err != nil || !h.verifyState(q.Get("state"), cookie.Value) {
+		logger.WarnContext(ctx, "invalid oauth state")
+		http.Error(w, "Invalid state. Start the installation again.", http.StatusBadRequest)
+
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{Name: oauthStateCookie, Path: "/slack", MaxAge: -1})
+
+	res, err := slack.OAuthV2Access(ctx, h.clientID, h.clientSecret, q.Get("code"), h.redirectURL, h.slackOptions...)
+	if err != nil {
+		logger.ErrorContext(ctx, "slack.OAuthV2Access", slog.Any("err", err))
+		http.Error(w, "Failed to install.", http.StatusBadGateway)
+
+		return
+	}
+
+	ins := &installation{
+		ID:          res.Team.ID,
+		TeamName:    res.Team.Name,
+		BotToken:    res.AccessToken,
+		BotUserID:   res.BotUserID,
+		Scope:       res.Scope,
+		InstalledBy: res.AuthedUser.ID,
+		InstalledAt: h.now(),
+	}
+
+	if res.Enterprise != nil {
+		ins.EnterpriseID = res.Enterprise.ID
+
+		if res.IsEnterpriseInstall {
+			ins.ID, ins.TeamName = res.Enterprise.ID, res.Enterprise.Name
+		}
+	}
+
+	if err := h.repo.save(ctx, ins); err != nil {
+		logger.ErrorContext(ctx, "h.repo.save", slog.Any("err", err))
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	h.clients.forget(ins.ID)
+
+	logger.InfoContext(ctx, "installed", slog.String("teamId", ins.ID), slog.String("userId", ins.InstalledBy))
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	if err := installedPage.Execute(w, ins.TeamName); err != nil {
+		logger.ErrorContext(ctx, "installedPage.Execute", slog.Any("err", err))
+	}
+}
+
+// state returns `<unix time>.<nonce>.<signature>`.
+func (h *oauthHandler) state(nonce string, at time.Time) string {
+	payload := strconv.FormatInt(at.Unix(), 10) + "." + nonce
+
+	return payload + "." + h.sign(payload)
+}
+
+func (h *oauthHandler) verifyState(state, nonce string) bool {
+	parts := strings.Split(state, ".")
+	if len(parts) != 3 || nonce == "" || parts[1] != nonce {
+		return false
+	}
+
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(h.sign(payload))) {
+		return false
+	}
+
+	unix, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return false
+	}
+
+	return h.now().Sub(time.Unix(unix, 0)) <= oauthStateTTL
+}
+
+func (h *oauthHandler) sign(payload string) string {
+	mac := hmac.New(sha256.New, []byte(h.clientSecret))
+	mac.Write([]byte(payload))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("rand.Read: %w", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
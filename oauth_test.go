@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/nownabe/moneysaver/slack"
+)
+
+func Test_oauthHandler_verifyState(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	h := &oauthHandler{clientSecret: "secret", now: func() time.Time { return now }}
+	other := &oauthHandler{clientSecret: "other"}
+
+	cases := map[string]struct {
+		state string
+		nonce string
+		ok    bool
+	}{
+		"valid":           {state: h.state("nonce", now.Add(-time.Minute)), nonce: "nonce", ok: true},
+		"expired":         {state: h.state("nonce", now.Add(-oauthStateTTL-time.Second)), nonce: "nonce"},
+		"other nonce":     {state: h.state("nonce", now), nonce: "other"},
+		"no cookie":       {state: h.state("", now)},
+		"other secret":    {state: other.state("nonce", now), nonce: "nonce"},
+		"malformed state": {state: "nonce", nonce: "nonce"},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if ok := h.verifyState(c.state, c.nonce); ok != c.ok {
+				t.Errorf("verifyState should be %v", c.ok)
+			}
+		})
+	}
+}
+
+func Test_oauthHandler_handleInstall(t *testing.T) {
+	t.Parallel()
+
+	h := &oauthHandler{clientID: "id", clientSecret: "secret", redirectURL: "https://example.com/slack/oauth_redirect", now: time.Now}
+
+	rec := httptest.NewRecorder()
+	h.handleInstall(rec, httptest.NewRequest(http.MethodGet, "/slack/install", nil))
+
+	if rec.Code != http.StatusFound {
+		t.Fatalf("status code should be 302, but %d", rec.Code)
+	}
+
+	u, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("url.Parse: %v", err)
+	}
+
+	q := u.Query()
+	if q.Get("client_id") != "id" || q.Get("redirect_uri") != h.redirectURL || q.Get("scope") == "" {
+		t.Errorf("incorrect parameters: %v", q)
+	}
+
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || !cookies[0].HttpOnly || !h.verifyState(q.Get("state"), cookies[0].Value) {
+		t.Errorf("state should be bound to the cookie: %v", cookies)
+	}
+}
+
+func Test_oauthHandler_handleRedirect(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fs := getFirestoreClient(t)
+	repo := &installationRepo{fs, nil}
+
+	defer flushStore(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true,"access_token":"xoxb-1","scope":"chat:write","bot_user_id":"U1",` +
+			`"team":{"id":"T0001","name":"team"},"enterprise":{"id":"E0001","name":"org"},"authed_user":{"id":"U0001"}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	h := &oauthHandler{
+		clientID:     "id",
+		clientSecret: "secret",
+		repo:         repo,
+		clients:      newTeamClients(repo),
+		slackOptions: []slack.Option{slack.WithBaseURL(srv.URL)},
+		now:          func() time.Time { return now },
+	}
+
+	cases := map[string]struct {
+		state  string
+		cookie string
+		code   int
+	}{
+		"invalid state": {state: h.state("nonce", now), cookie: "other", code: http.StatusBadRequest},
+		"installed":     {state: h.state("nonce", now), cookie: "nonce", code: http.StatusOK},
+	}
+
+	for name, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/slack/oauth_redirect?code=code&state="+url.QueryEscape(c.state), nil)
+		req.AddCookie(&http.Cookie{Name: oauthStateCookie, Value: c.cookie})
+
+		rec := httptest.NewRecorder()
+		h.handleRedirect(rec, req)
+
+		if rec.Code != c.code {
+			t.Errorf("%s: status code should be %d, but %d", name, c.code, rec.Code)
+		}
+	}
+
+	ins, err := repo.findByID(ctx, "T0001")
+	if err != nil {
+		t.Fatalf("repo.findByID: %v", err)
+	}
+
+	if ins.BotToken != "xoxb-1" || ins.EnterpriseID != "E0001" || ins.InstalledBy != "U0001" || !ins.InstalledAt.Equal(now) {
+		t.Errorf("incorrect installation: %+v", ins)
+	}
+}
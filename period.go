@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// Custom periods are limited so that positions don't read too many months.
+const maxPeriodMonths = 24
+
+// periodBudget is a budget of every calendar year or of a custom period of months.
+type periodBudget struct {
+	Name   string `firestore:"name"`
+	Amount int64  `firestore:"amount"`
+	Yearly bool   `firestore:"yearly,omitempty"`
+	// First and last months of custom periods
+	Start string `firestore:"start,omitempty"`
+	End   string `firestore:"end,omitempty"`
+	// Counts only expenditures in the category. Empty means all.
+	Category string `firestore:"category,omitempty"`
+}
+
+// span returns the first and last months of the period which contains the month.
+func (b *periodBudget) span(month string) (string, string, bool) {
+	if b.Yearly {
+		year, _, _ := strings.Cut(month, "-")
+		return year + "-01", year + "-12", true
+	}
+
+	if b.Start <= month && month <= b.End {
+		return b.Start, b.End, true
+	}
+
+	return "", "", false
+}
+
+func (b *periodBudget) label(l lang) string {
+	s := b.Name
+	if b.Category != "" {
+		s += " #" + b.Category
+	}
+
+	if b.Yearly {
+		return s + " (" + l.t(msgPeriodYearly) + ")"
+	}
+
+	return s + " (" + b.Start + "〜" + b.End + ")"
+}
+
+// budgetPosition is spending to date against a budget.
+type budgetPosition struct {
+	label  string
+	total  int64
+	budget int64
+}
+
+// monthsBetween returns months from start to end inclusive.
+func monthsBetween(start, end string) ([]string, error) {
+	s, err := time.Parse(monthLayout, start)
+	if err != nil {
+		return nil, fmt.Errorf("time.Parse: %w", err)
+	}
+
+	e, err := time.Parse(monthLayout, end)
+	if err != nil {
+		return nil, fmt.Errorf("time.Parse: %w", err)
+	}
+
+	var months []string
+	for m := s; !m.After(e); m = m.AddDate(0, 1, 0) {
+		months = append(months, m.Format(monthLayout))
+	}
+
+	return months, nil
+}
+
+// budgetPositions returns the year-to-date position of the monthly budget and positions of periods containing the month.
+func budgetPositions(
+	ctx context.Context, totals *monthTotals, l lang, ch *channel, month string,
+) ([]budgetPosition, error) {
+	total := func(start, end, category string) (int64, error) {
+		months, err := monthsBetween(start, end)
+		if err != nil {
+			return 0, err
+		}
+
+		if err := totals.prefetch(ctx, months, category); err != nil {
+			return 0, fmt.Errorf("totals.prefetch: %w", err)
+		}
+
+		var t int64
+
+		for _, m := range months {
+			mt, err := totals.get(ctx, m, category)
+			if err != nil {
+				return 0, err
+			}
+
+			t += mt.amount
+		}
+
+		return t, nil
+	}
+
+	year, _, _ := strings.Cut(month, "-")
+
+	ytdMonths, err := monthsBetween(year+"-01", month)
+	if err != nil {
+		return nil, err
+	}
+
+	ytd := budgetPosition{label: l.t(msgFieldYearToDate)}
+	for _, m := range ytdMonths {
+		ytd.budget += ch.budgetFor(m)
+	}
+
+	if ytd.total, err = total(year+"-01", month, ""); err != nil {
+		return nil, err
+	}
+
+	positions := []budgetPosition{ytd}
+
+	for i := range ch.Periods {
+		b := &ch.Periods[i]
+
+		start, end, ok := b.span(month)
+		if !ok {
+			continue
+		}
+
+		// To date
+		if end > month {
+			end = month
+		}
+
+		t, err := total(start, end, b.Category)
+		if err != nil {
+			return nil, err
+		}
+
+		positions = append(positions, budgetPosition{label: b.label(l), total: t, budget: b.Amount})
+	}
+
+	return positions, nil
+}
+
+// periodHandlers are subcommands of /moneysaver period.
+var periodHandlers = map[string]commandHandler{
+	"add":    (*commandProcessor).processPeriodAdd,
+	"list":   (*commandProcessor).processPeriodList,
+	"remove": (*commandProcessor).processPeriodRemove,
+}
+
+// processPeriod manages budgets of years or custom periods.
+func (p *commandProcessor) processPeriod(
+	ctx context.Context, c slack.SlashCommand, l lang, ch *channel, args []string,
+) (*slack.Msg, error) {
+	if len(args) == 0 {
+		return &slack.Msg{Text: l.t(msgPeriodUsage)}, nil
+	}
+
+	h, ok := periodHandlers[args[0]]
+	if !ok {
+		return &slack.Msg{Text: l.t(msgPeriodUsage)}, nil
+	}
+
+	if ch == nil {
+		return &slack.Msg{Text: l.t(msgSetBudgetFirst)}, nil
+	}
+
+	return h(p, ctx, c, l, ch, args[1:])
+}
+
+// processPeriodAdd adds or replaces a budget like `travel 300000 yearly #travel` or `summer 200000 2026-07..2026-09`.
+func (p *commandProcessor) processPeriodAdd(
+	ctx context.Context, c slack.SlashCommand, l lang, ch *channel, args []string,
+) (*slack.Msg, error) {
+	if !ch.allows(c.UserID, ch.CanSetBudget) {
+		return notAllowed(l, ch.CanSetBudget), nil
+	}
+
+	b, ok := parsePeriodBudget(args)
+	if !ok {
+		return &slack.Msg{Text: l.t(msgPeriodUsage)}, nil
+	}
+
+	periods := make([]periodBudget, 0, len(ch.Periods)+1)
+	for _, pb := range ch.Periods {
+		if pb.Name != b.Name {
+			periods = append(periods, pb)
+		}
+	}
+
+	ch.Periods = append(periods, *b)
+
+	if err := p.channelRepo.save(ctx, ch); err != nil {
+		return nil, wrap(http.StatusInternalServerError, "p.channelRepo.save: %w", err)
+	}
+
+	return &slack.Msg{Text: l.t(msgPeriodAdded, b.label(l))}, nil
+}
+
+// processPeriodList lists budgets with their positions to date in this month.
+func (p *commandProcessor) processPeriodList(
+	ctx context.Context, _ slack.SlashCommand, l lang, ch *channel, args []string,
+) (*slack.Msg, error) {
+	if len(args) != 0 {
+		return &slack.Msg{Text: l.t(msgPeriodUsage)}, nil
+	}
+
+	if len(ch.Periods) == 0 {
+		return &slack.Msg{Text: l.t(msgPeriodEmpty)}, nil
+	}
+
+	cs := p.settings.get().channel(ch.ID)
+	view := *ch
+	cs.applyTo(&view)
+
+	month := time.Now().In(cs.location()).Format(monthLayout)
+
+	positions, err := budgetPositions(ctx, newMonthTotals(p.expenditureRepo, ch.ID), l, &view, month)
+	if err != nil {
+		return nil, wrap(http.StatusInternalServerError, "budgetPositions: %w", err)
+	}
+
+	cur := view.currency()
+
+	lines := []string{l.t(msgPeriodListTitle)}
+	for _, pos := range positions {
+		lines = append(lines, "• "+pos.label+": "+formatPosition(pos, cur))
+	}
+
+	return &slack.Msg{Text: strings.Join(lines, "\n")}, nil
+}
+
+func (p *commandProcessor) processPeriodRemove(
+	ctx context.Context, c slack.SlashCommand, l lang, ch *channel, args []string,
+) (*slack.Msg, error) {
+	if !ch.allows(c.UserID, ch.CanSetBudget) {
+		return notAllowed(l, ch.CanSetBudget), nil
+	}
+
+	if len(args) != 1 {
+		return &slack.Msg{Text: l.t(msgPeriodUsage)}, nil
+	}
+
+	periods := make([]periodBudget, 0, len(ch.Periods))
+	for _, pb := range ch.Periods {
+		if pb.Name != args[0] {
+			periods = append(periods, pb)
+		}
+	}
+
+	if len(periods) == len(ch.Periods) {
+		return &slack.Msg{Text: l.t(msgPeriodNotFound, args[0])}, nil
+	}
+
+	ch.Periods = periods
+
+	if err := p.channelRepo.save(ctx, ch); err != nil {
+		return nil, wrap(http.StatusInternalServerError, "p.channelRepo.save: %w", err)
+	}
+
+	return &slack.Msg{Text: l.t(msgPeriodRemoved, args[0])}, nil
+}
+
+// parsePeriodBudget parses `<name> <amount> <yearly|YYYY-MM..YYYY-MM> [#category]`.
+func parsePeriodBudget(args []string) (*periodBudget, bool) {
+	if len(args) != 3 && len(args) != 4 {
+		return nil, false
+	}
+
+	amount, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil || amount <= 0 {
+		return nil, false
+	}
+
+	b := &periodBudget{Name: args[0], Amount: amount}
+
+	if args[2] == "yearly" {
+		b.Yearly = true
+	} else {
+		start, end, ok := strings.Cut(args[2], "..")
+		if !ok || start > end {
+			return nil, false
+		}
+
+		if months, err := monthsBetween(start, end); err != nil || len(months) > maxPeriodMonths {
+			return nil, false
+		}
+
+		b.Start, b.End = start, end
+	}
+
+	if len(args) == 4 {
+		c, ok := parseCategory(args[3])
+		if !ok {
+			return nil, false
+		}
+
+		b.Category = c
+	}
+
+	return b, true
+}
+
+func formatPosition(pos budgetPosition, cur string) string {
+	return humanizeIn(pos.total, cur) + " / " + humanizeIn(pos.budget, cur)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_parsePeriodBudget(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		text string
+		e    *periodBudget
+	}{
+		"yearly":         {text: "travel 300000 yearly #travel", e: &periodBudget{Name: "travel", Amount: 300000, Yearly: true, Category: "travel"}},
+		"custom":         {text: "summer 200000 2026-07..2026-09", e: &periodBudget{Name: "summer", Amount: 200000, Start: "2026-07", End: "2026-09"}},
+		"no period":      {text: "travel 300000"},
+		"invalid amount": {text: "travel abc yearly"},
+		"reversed":       {text: "summer 200000 2026-09..2026-07"},
+		"invalid month":  {text: "summer 200000 2026-07..2026-13"},
+		"longest":        {text: "long 200000 2025-01..2026-12", e: &periodBudget{Name: "long", Amount: 200000, Start: "2025-01", End: "2026-12"}},
+		"too long":       {text: "long 200000 2020-01..2040-12"},
+		"no category":    {text: "travel 300000 yearly travel"},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			b, ok := parsePeriodBudget(strings.Fields(c.text))
+			if ok != (c.e != nil) {
+				t.Fatalf("incorrect result: %v", b)
+			}
+
+			if c.e != nil && !reflect.DeepEqual(b, c.e) {
+				t.Errorf("expected %+v, but %+v", c.e, b)
+			}
+		})
+	}
+}
+
+func Test_periodBudget_span(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		b          *periodBudget
+		month      string
+		start, end string
+		ok         bool
+	}{
+		"yearly":  {b: &periodBudget{Yearly: true}, month: "2026-10", start: "2026-01", end: "2026-12", ok: true},
+		"inside":  {b: &periodBudget{Start: "2026-07", End: "2026-09"}, month: "2026-09", start: "2026-07", end: "2026-09", ok: true},
+		"outside": {b: &periodBudget{Start: "2026-07", End: "2026-09"}, month: "2026-10"},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			start, end, ok := c.b.span(c.month)
+			if start != c.start || end != c.end || ok != c.ok {
+				t.Errorf("incorrect span: %s, %s, %v", start, end, ok)
+			}
+		})
+	}
+}
+
+func Test_budgetPositions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fs := getFirestoreClient(t)
+	r := &expenditureRepo{fs}
+
+	defer flushStore(t)
+
+	for _, ex := range []*expenditure{
+		{Channel: "ch1", TS: "1", Amount: 50000, Category: "travel", Timestamp: time.Date(2026, 8, 10, 0, 0, 0, 0, time.UTC)},
+		{Channel: "ch1", TS: "2", Amount: 3000, Category: "food", Timestamp: time.Date(2026, 9, 10, 0, 0, 0, 0, time.UTC)},
+		{Channel: "ch1", TS: "3", Amount: 2000, Category: "travel", Timestamp: time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC)},
+		{Channel: "ch1", TS: "4", Amount: 9000, Timestamp: time.Date(2025, 12, 10, 0, 0, 0, 0, time.UTC)},
+	} {
+		if err := r.add(ctx, ex); err != nil {
+			t.Fatalf("r.add: %v", err)
+		}
+	}
+
+	ch := &channel{ID: "ch1", Budget: 10000, Periods: []periodBudget{
+		{Name: "travel", Amount: 300000, Yearly: true, Category: "travel"},
+		{Name: "summer", Amount: 100000, Start: "2026-07", End: "2026-09"},
+	}}
+
+	positions, err := budgetPositions(ctx, newMonthTotals(r, "ch1"), langEN, ch, "2026-09")
+	if err != nil {
+		t.Fatalf("budgetPositions: %v", err)
+	}
+
+	e := []budgetPosition{
+		{label: "Year to date", total: 53000, budget: 90000},
+		{label: "travel #travel (yearly)", total: 50000, budget: 300000},
+		{label: "summer (2026-07〜2026-09)", total: 53000, budget: 100000},
+	}
+
+	if !reflect.DeepEqual(positions, e) {
+		t.Errorf("expected %+v, but %+v", e, positions)
+	}
+
+	positions, err = budgetPositions(ctx, newMonthTotals(r, "ch1"), langEN, &channel{ID: "ch1", Budget: 10000}, "2026-09")
+	if err != nil || !reflect.DeepEqual(positions, e[:1]) {
+		t.Errorf("channels without periods should have the year-to-date position: %+v, %v", positions, err)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// role is who can do an action in a channel.
+type role string
+
+const (
+	roleAnyone role = "anyone"
+	roleAdmins role = "admins"
+	roleOwners role = "owners"
+)
+
+func parseRole(s string) (role, bool) {
+	switch r := role(s); r {
+	case roleAnyone, roleAdmins, roleOwners:
+		return r, true
+	}
+
+	return "", false
+}
+
+func (r role) label(l lang) string {
+	switch r {
+	case roleOwners:
+		return l.t(msgRoleOwners)
+	case roleAdmins:
+		return l.t(msgRoleAdmins)
+	}
+
+	return l.t(msgRoleAnyone)
+}
+
+// allows reports whether the user has the role in the channel.
+// Channels without documents have no restrictions.
+func (ch *channel) allows(userID string, r role) bool {
+	if ch == nil {
+		return true
+	}
+
+	switch r {
+	case roleOwners:
+		return ch.isOwner(userID)
+	case roleAdmins:
+		return ch.isOwner(userID) || slices.Contains(ch.Admins, userID)
+	}
+
+	return true
+}
+
+// isOwner reports whether the user can manage permissions. Anyone can while there are no owners.
+func (ch *channel) isOwner(userID string) bool {
+	return len(ch.Owners) == 0 || slices.Contains(ch.Owners, userID)
+}
+
+// canDelete reports whether the user can delete, restore or change the expenditure.
+// Changing others' is restricted as well since changing the amount to 0 is the same as deleting.
+// Expenditures recorded before authors were stored are regarded as others'.
+func (ch *channel) canDelete(userID string, ex *expenditure) bool {
+	return (ex.User != "" && ex.User == userID) || ch.allows(userID, ch.CanDeleteOthers)
+}
+
+// canCancel reports whether the user can cancel the recurring item.
+func (ch *channel) canCancel(userID string, rc *recurring) bool {
+	return rc.CreatedBy == userID || ch.allows(userID, ch.CanDeleteOthers)
+}
+
+// notAllowed returns the reply to users who don't have the role.
+func notAllowed(l lang, r role) *slack.Msg {
+	return &slack.Msg{Text: l.t(msgNotAllowed, r.label(l))}
+}
+
+// parseUserMention parses `<@U0123>` or `<@U0123|name>` escaped by Slack.
+func parseUserMention(s string) (string, bool) {
+	if !strings.HasPrefix(s, "<@") || !strings.HasSuffix(s, ">") {
+		return "", false
+	}
+
+	id, _, _ := strings.Cut(s[2:len(s)-1], "|")
+	if id == "" {
+		return "", false
+	}
+
+	return id, true
+}
+
+// processPermissions shows or changes permissions of the channel.
+// The first user to change them becomes an owner.
+func (p *commandProcessor) processPermissions(
+	ctx context.Context, c slack.SlashCommand, l lang, ch *channel, args []string,
+) (*slack.Msg, error) {
+	if ch == nil {
+		return &slack.Msg{Text: l.t(msgSetBudgetFirst)}, nil
+	}
+
+	if len(args) == 0 {
+		return &slack.Msg{Text: formatPermissions(l, ch)}, nil
+	}
+
+	if !ch.isOwner(c.UserID) {
+		return notAllowed(l, roleOwners), nil
+	}
+
+	if !applyPermission(ch, args) {
+		return &slack.Msg{Text: l.t(msgPermissionsUsage)}, nil
+	}
+
+	if len(ch.Owners) == 0 {
+		ch.Owners = []string{c.UserID}
+	}
+
+	if err := p.channelRepo.save(ctx, ch); err != nil {
+		return nil, wrap(http.StatusInternalServerError, "p.channelRepo.save: %w", err)
+	}
+
+	return &slack.Msg{Text: l.t(msgPermissionsUpdated) + "\n" + formatPermissions(l, ch)}, nil
+}
+
+// applyPermission applies `owner|admin add|remove <@user>` or `budget|delete <role>` to the channel.
+func applyPermission(ch *channel, args []string) bool {
+	switch {
+	case len(args) == 3 && (args[0] == "owner" || args[0] == "admin"):
+		id, ok := parseUserMention(args[2])
+		if !ok {
+			return false
+		}
+
+		users := &ch.Owners
+		if args[0] == "admin" {
+			users = &ch.Admins
+		}
+
+		switch args[1] {
+		case "add":
+			if !slices.Contains(*users, id) {
+				*users = append(*users, id)
+			}
+		case "remove":
+			*users = slices.DeleteFunc(*users, func(u string) bool { return u == id })
+		default:
+			return false
+		}
+
+		return true
+	case len(args) == 2 && (args[0] == "budget" || args[0] == "delete"):
+		r, ok := parseRole(args[1])
+		if !ok {
+			return false
+		}
+
+		if args[0] == "budget" {
+			ch.CanSetBudget = r
+		} else {
+			ch.CanDeleteOthers = r
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func formatPermissions(l lang, ch *channel) string {
+	users := func(ids []string) string {
+		if len(ids) == 0 {
+			return l.t(msgNobody)
+		}
+
+		mentions := make([]string, len(ids))
+		for i, id := range ids {
+			mentions[i] = "<@" + id + ">"
+		}
+
+		return strings.Join(mentions, " ")
+	}
+
+	return strings.Join([]string{
+		l.t(msgPermissionsTitle),
+		"• " + l.t(msgPermissionsOwners, users(ch.Owners)),
+		"• " + l.t(msgPermissionsAdmins, users(ch.Admins)),
+		"• " + l.t(msgPermissionsSetBudget, ch.CanSetBudget.label(l)),
+		"• " + l.t(msgPermissionsDeleteOthers, ch.CanDeleteOthers.label(l)),
+	}, "\n")
+}
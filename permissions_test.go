@@ -0,0 +1,134 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_channel_allows(t *testing.T) {
+	t.Parallel()
+
+	ch := &channel{Owners: []string{"U1"}, Admins: []string{"U2"}}
+
+	cases := map[string]struct {
+		ch   *channel
+		user string
+		role role
+		e    bool
+	}{
+		"no document":        {ch: nil, user: "U3", role: roleOwners, e: true},
+		"anyone":             {ch: ch, user: "U3", role: "", e: true},
+		"owner as owner":     {ch: ch, user: "U1", role: roleOwners, e: true},
+		"admin as owner":     {ch: ch, user: "U2", role: roleOwners},
+		"owner as admin":     {ch: ch, user: "U1", role: roleAdmins, e: true},
+		"admin as admin":     {ch: ch, user: "U2", role: roleAdmins, e: true},
+		"member as admin":    {ch: ch, user: "U3", role: roleAdmins},
+		"no owners as owner": {ch: &channel{}, user: "U3", role: roleOwners, e: true},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if a := c.ch.allows(c.user, c.role); a != c.e {
+				t.Errorf("allows should be %v", c.e)
+			}
+		})
+	}
+}
+
+func Test_channel_canDelete(t *testing.T) {
+	t.Parallel()
+
+	ch := &channel{Owners: []string{"U1"}, CanDeleteOthers: roleOwners}
+
+	cases := map[string]struct {
+		user string
+		ex   *expenditure
+		e    bool
+	}{
+		"own":      {user: "U2", ex: &expenditure{User: "U2"}, e: true},
+		"others":   {user: "U2", ex: &expenditure{User: "U3"}},
+		"unknown":  {user: "U2", ex: &expenditure{}},
+		"by owner": {user: "U1", ex: &expenditure{User: "U3"}, e: true},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if a := ch.canDelete(c.user, c.ex); a != c.e {
+				t.Errorf("canDelete should be %v", c.e)
+			}
+		})
+	}
+}
+
+func Test_channel_canCancel(t *testing.T) {
+	t.Parallel()
+
+	ch := &channel{Owners: []string{"U1"}, CanDeleteOthers: roleOwners}
+
+	cases := map[string]struct {
+		user string
+		rc   *recurring
+		e    bool
+	}{
+		"own":      {user: "U2", rc: &recurring{CreatedBy: "U2"}, e: true},
+		"others":   {user: "U2", rc: &recurring{CreatedBy: "U3"}},
+		"by owner": {user: "U1", rc: &recurring{CreatedBy: "U3"}, e: true},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if a := ch.canCancel(c.user, c.rc); a != c.e {
+				t.Errorf("canCancel should be %v", c.e)
+			}
+		})
+	}
+}
+
+func Test_applyPermission(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		ch   *channel
+		text string
+		e    *channel
+	}{
+		"add owner":       {ch: &channel{Owners: []string{"U1"}}, text: "owner add <@U2|bob>", e: &channel{Owners: []string{"U1", "U2"}}},
+		"add owner twice": {ch: &channel{Owners: []string{"U1"}}, text: "owner add <@U1>", e: &channel{Owners: []string{"U1"}}},
+		"remove admin":    {ch: &channel{Admins: []string{"U1", "U2"}}, text: "admin remove <@U1>", e: &channel{Admins: []string{"U2"}}},
+		"budget":          {ch: &channel{}, text: "budget admins", e: &channel{CanSetBudget: roleAdmins}},
+		"delete":          {ch: &channel{}, text: "delete owners", e: &channel{CanDeleteOthers: roleOwners}},
+		"not mention":     {ch: &channel{}, text: "owner add bob"},
+		"unknown role":    {ch: &channel{}, text: "budget everyone"},
+		"unknown op":      {ch: &channel{}, text: "owner invite <@U1>"},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ok := applyPermission(c.ch, strings.Fields(c.text))
+			if ok != (c.e != nil) {
+				t.Fatalf("applyPermission should be %v", c.e != nil)
+			}
+
+			if c.e != nil && !reflect.DeepEqual(c.ch, c.e) {
+				t.Errorf("expected %+v, but %+v", c.e, c.ch)
+			}
+		})
+	}
+}
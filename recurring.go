@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// recurringHandlers are subcommands of /moneysaver recurring.
+var recurringHandlers = map[string]commandHandler{
+	"add":    (*commandProcessor).processRecurringAdd,
+	"list":   (*commandProcessor).processRecurringList,
+	"cancel": (*commandProcessor).processRecurringCancel,
+}
+
+// processRecurring manages recurring items of the channel.
+func (p *commandProcessor) processRecurring(
+	ctx context.Context, c slack.SlashCommand, l lang, ch *channel, args []string,
+) (*slack.Msg, error) {
+	if len(args) == 0 {
+		return &slack.Msg{Text: l.t(msgRecurringUsage)}, nil
+	}
+
+	h, ok := recurringHandlers[args[0]]
+	if !ok {
+		return &slack.Msg{Text: l.t(msgRecurringUsage)}, nil
+	}
+
+	if ch == nil {
+		return &slack.Msg{Text: l.t(msgSetBudgetFirst)}, nil
+	}
+
+	return h(p, ctx, c, l, ch, args[1:])
+}
+
+// processRecurringAdd registers an item like `1480 #subscriptions Netflix on 5`.
+func (p *commandProcessor) processRecurringAdd(
+	ctx context.Context, c slack.SlashCommand, l lang, ch *channel, args []string,
+) (*slack.Msg, error) {
+	rc, ok := parseRecurring(args)
+	if !ok {
+		return &slack.Msg{Text: l.t(msgRecurringUsage)}, nil
+	}
+
+	if rc.Currency != "" {
+		if _, err := p.rates.rate(ch, rc.Currency); err != nil {
+			return &slack.Msg{Text: l.t(msgUnknownRate, rc.Currency)}, nil
+		}
+	}
+
+	now := time.Now().In(p.settings.get().channel(ch.ID).location())
+
+	rc.Channel = ch.ID
+	rc.Enterprise = teamFrom(ctx).enterpriseID
+	rc.StartMonth = startMonth(rc.Day, now)
+	rc.CreatedBy = c.UserID
+	rc.CreatedAt = now
+
+	if err := p.recurringRepo.add(ctx, rc); err != nil {
+		return nil, wrap(http.StatusInternalServerError, "p.recurringRepo.add: %w", err)
+	}
+
+	return &slack.Msg{Text: l.t(msgRecurringAdded, rc.Name, rc.Day, rc.ID)}, nil
+}
+
+func (p *commandProcessor) processRecurringList(
+	ctx context.Context, _ slack.SlashCommand, l lang, ch *channel, args []string,
+) (*slack.Msg, error) {
+	if len(args) != 0 {
+		return &slack.Msg{Text: l.t(msgRecurringUsage)}, nil
+	}
+
+	rcs, err := p.recurringRepo.list(ctx, ch.ID)
+	if err != nil {
+		return nil, wrap(http.StatusInternalServerError, "p.recurringRepo.list: %w", err)
+	}
+
+	if len(rcs) == 0 {
+		return &slack.Msg{Text: l.t(msgRecurringEmpty)}, nil
+	}
+
+	lines := []string{l.t(msgRecurringListTitle)}
+
+	for _, rc := range rcs {
+		amount := formatAmount(rc.Amount, rc.Currency)
+		if rc.Currency == "" {
+			amount = humanizeIn(int64(rc.Amount), ch.currency())
+		}
+
+		line := "• " + l.t(msgRecurringItem, rc.ID, rc.Name, amount, rc.Day)
+		if rc.Category != "" {
+			line += " #" + rc.Category
+		}
+
+		lines = append(lines, line)
+	}
+
+	return &slack.Msg{Text: strings.Join(lines, "\n")}, nil
+}
+
+func (p *commandProcessor) processRecurringCancel(
+	ctx context.Context, c slack.SlashCommand, l lang, ch *channel, args []string,
+) (*slack.Msg, error) {
+	if len(args) != 1 {
+		return &slack.Msg{Text: l.t(msgRecurringUsage)}, nil
+	}
+
+	rc, err := p.recurringRepo.findByID(ctx, ch.ID, args[0])
+	if errors.Is(err, errNotFound) {
+		return &slack.Msg{Text: l.t(msgRecurringNotFound, args[0])}, nil
+	} else if err != nil {
+		return nil, wrap(http.StatusInternalServerError, "p.recurringRepo.findByID: %w", err)
+	}
+
+	if !ch.canCancel(c.UserID, rc) {
+		return &slack.Msg{Text: l.t(msgCannotCancelOthers, ch.CanDeleteOthers.label(l))}, nil
+	}
+
+	err = p.recurringRepo.delete(ctx, ch.ID, args[0])
+	if errors.Is(err, errNotFound) {
+		return &slack.Msg{Text: l.t(msgRecurringNotFound, args[0])}, nil
+	} else if err != nil {
+		return nil, wrap(http.StatusInternalServerError, "p.recurringRepo.delete: %w", err)
+	}
+
+	return &slack.Msg{Text: l.t(msgRecurringCanceled, args[0])}, nil
+}
+
+// parseRecurring parses `<amount> [#category] <name> on <day>`.
+// Amounts may have currencies like `USD 9.99`, and categories may be channel mentions escaped by Slack.
+func parseRecurring(args []string) (*recurring, bool) {
+	n := len(args)
+	if n < 4 || args[n-2] != "on" {
+		return nil, false
+	}
+
+	day, err := strconv.Atoi(args[n-1])
+	if err != nil || day < 1 || day > 31 {
+		return nil, false
+	}
+
+	rc := &recurring{Day: day}
+
+	rest := args[:n-2]
+
+	for i := 2; i >= 1; i-- {
+		if i >= len(rest) {
+			continue
+		}
+
+		a, err := parseAmount(strings.Join(rest[:i], " "))
+		if err != nil || a.float() <= 0 {
+			continue
+		}
+
+		rc.Amount, rc.Currency, rest = a.float(), a.currency, rest[i:]
+
+		break
+	}
+
+	if rc.Amount == 0 || len(rest) == 0 {
+		return nil, false
+	}
+
+	if c, ok := parseCategory(rest[0]); ok {
+		rc.Category, rest = c, rest[1:]
+	}
+
+	rc.Name = strings.Join(rest, " ")
+	if rc.Name == "" {
+		return nil, false
+	}
+
+	return rc, true
+}
+
+// parseCategory parses `#category` or `<#C0123|category>`.
+func parseCategory(s string) (string, bool) {
+	if strings.HasPrefix(s, "<#") && strings.HasSuffix(s, ">") {
+		if _, name, ok := strings.Cut(s[2:len(s)-1], "|"); ok && name != "" {
+			return name, true
+		}
+
+		return "", false
+	}
+
+	if c := strings.TrimPrefix(s, "#"); c != s && c != "" {
+		return c, true
+	}
+
+	return "", false
+}
+
+// recurringScheduler records recurring items on their due dates.
+type recurringScheduler struct {
+	*eventProcessor
+	recurringRepo *recurringRepo
+}
+
+// run records due items periodically until ctx is done.
+func (s *recurringScheduler) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.materialize(ctx, time.Now()); err != nil {
+			logger.ErrorContext(ctx, "failed to record recurring items", slog.Any("err", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// materialize records items due by now into the months they're due and replies them.
+// It's safe to run concurrently since each month is recorded in a transaction only once.
+func (s *recurringScheduler) materialize(ctx context.Context, now time.Time) (err error) {
+	ctx, end := startSpan(ctx, "materializeRecurring")
+	defer end(&err)
+
+	rcs, err := s.recurringRepo.findAll(ctx)
+	if err != nil {
+		return fmt.Errorf("s.recurringRepo.findAll: %w", err)
+	}
+
+	for _, rc := range rcs {
+		if err := s.materializeItem(ctx, rc, now); err != nil {
+			logger.ErrorContext(ctx, "failed to record recurring item", slog.String("teamId", rc.Team),
+				slog.String("channelId", rc.Channel), slog.String("recurringId", rc.ID), slog.Any("err", err))
+		}
+	}
+
+	return nil
+}
+
+func (s *recurringScheduler) materializeItem(ctx context.Context, rc *recurring, now time.Time) (err error) {
+	ctx, end := startSpan(ctx, "materializeRecurringItem",
+		attribute.String("slack.channel", rc.Channel), attribute.String("recurring", rc.ID))
+	defer end(&err)
+
+	if rc.Team != "" {
+		ctx = withTeam(ctx, rc.Team, rc.Enterprise)
+	}
+
+	ctx = withActor(ctx, rc.CreatedBy, "recurring:"+rc.ID)
+
+	ch, err := s.channelRepo.findByID(ctx, rc.Channel)
+	if errors.Is(err, errNotFound) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("s.channelRepo.findByID: %w", err)
+	}
+
+	cs := s.settings.get().channel(ch.ID)
+	cs.applyTo(ch)
+
+	loc := cs.location()
+
+	for _, month := range rc.dueMonths(now.In(loc), loc) {
+		ex, err := rc.expenditure(month, now, loc)
+		if err != nil {
+			return fmt.Errorf("rc.expenditure: %w", err)
+		}
+
+		if err := s.rates.convert(ch, ex); err != nil {
+			return fmt.Errorf("s.rates.convert: %w", err)
+		}
+
+		recorded, err := s.recurringRepo.materialize(ctx, rc, month, ex)
+		if errors.Is(err, errNotFound) {
+			// Canceled meanwhile
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("s.recurringRepo.materialize: %w", err)
+		}
+
+		rc.LastMonth = month
+
+		if !recorded {
+			continue
+		}
+
+		expendituresTotal.WithLabelValues(string(opAdded)).Inc()
+
+		if err := s.replyTotal(ctx, ch, cs, rc.CreatedBy, ex, opAdded); err != nil {
+			return fmt.Errorf("s.replyTotal: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_parseRecurring(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		text string
+		e    *recurring
+	}{
+		"category":       {text: "1480 #subscriptions Netflix on 5", e: &recurring{Amount: 1480, Category: "subscriptions", Name: "Netflix", Day: 5}},
+		"no category":    {text: "80000 Rent on 27", e: &recurring{Amount: 80000, Name: "Rent", Day: 27}},
+		"name of words":  {text: "2980 Phone bill on 31", e: &recurring{Amount: 2980, Name: "Phone bill", Day: 31}},
+		"currency":       {text: "USD 9.99 #subscriptions Spotify on 1", e: &recurring{Amount: 9.99, Currency: "USD", Category: "subscriptions", Name: "Spotify", Day: 1}},
+		"mention":        {text: "1480 <#C0123|subscriptions> Netflix on 5", e: &recurring{Amount: 1480, Category: "subscriptions", Name: "Netflix", Day: 5}},
+		"name like code": {text: "1480 Car insurance on 5", e: &recurring{Amount: 1480, Name: "Car insurance", Day: 5}},
+		"no name":        {text: "1480 #subscriptions on 5"},
+		"no day":         {text: "1480 Netflix"},
+		"invalid day":    {text: "1480 Netflix on 32"},
+		"zero day":       {text: "1480 Netflix on 0"},
+		"no amount":      {text: "Netflix on 5"},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rc, ok := parseRecurring(strings.Fields(c.text))
+			if ok != (c.e != nil) {
+				t.Fatalf("incorrect result: %v", rc)
+			}
+
+			if c.e != nil && !reflect.DeepEqual(rc, c.e) {
+				t.Errorf("expected %+v, but %+v", c.e, rc)
+			}
+		})
+	}
+}
+
+func Test_recurring_dueMonths(t *testing.T) {
+	t.Parallel()
+
+	loc := time.FixedZone("JST", 9*60*60)
+
+	cases := map[string]struct {
+		rc  *recurring
+		now time.Time
+		e   []string
+	}{
+		"before due":   {rc: &recurring{Day: 5, StartMonth: "2026-10"}, now: time.Date(2026, 10, 4, 23, 59, 0, 0, loc)},
+		"on due":       {rc: &recurring{Day: 5, StartMonth: "2026-10"}, now: time.Date(2026, 10, 5, 0, 0, 0, 0, loc), e: []string{"2026-10"}},
+		"recorded":     {rc: &recurring{Day: 5, StartMonth: "2026-10", LastMonth: "2026-10"}, now: time.Date(2026, 10, 19, 0, 0, 0, 0, loc)},
+		"catch up":     {rc: &recurring{Day: 5, StartMonth: "2026-08", LastMonth: "2026-08"}, now: time.Date(2026, 10, 19, 0, 0, 0, 0, loc), e: []string{"2026-09", "2026-10"}},
+		"end of month": {rc: &recurring{Day: 31, StartMonth: "2026-02"}, now: time.Date(2026, 2, 28, 0, 0, 0, 0, loc), e: []string{"2026-02"}},
+		"next year":    {rc: &recurring{Day: 1, StartMonth: "2026-12", LastMonth: "2026-12"}, now: time.Date(2027, 1, 1, 0, 0, 0, 0, loc), e: []string{"2027-01"}},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if a := c.rc.dueMonths(c.now, loc); !reflect.DeepEqual(a, c.e) {
+				t.Errorf("expected %v, but %v", c.e, a)
+			}
+		})
+	}
+}
+
+func Test_startMonth(t *testing.T) {
+	t.Parallel()
+
+	loc := time.FixedZone("JST", 9*60*60)
+
+	cases := map[string]struct {
+		day int
+		now time.Time
+		e   string
+	}{
+		"not due yet":  {day: 25, now: time.Date(2026, 10, 19, 12, 0, 0, 0, loc), e: "2026-10"},
+		"due today":    {day: 19, now: time.Date(2026, 10, 19, 12, 0, 0, 0, loc), e: "2026-10"},
+		"already due":  {day: 5, now: time.Date(2026, 10, 19, 12, 0, 0, 0, loc), e: "2026-11"},
+		"end of month": {day: 31, now: time.Date(2026, 11, 30, 12, 0, 0, 0, loc), e: "2026-11"},
+		"next year":    {day: 1, now: time.Date(2026, 12, 19, 12, 0, 0, 0, loc), e: "2027-01"},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if a := startMonth(c.day, c.now); a != c.e {
+				t.Errorf("expected %s, but %s", c.e, a)
+			}
+		})
+	}
+}
+
+func Test_recurringScheduler_materialize(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fs := getFirestoreClient(t)
+	mock := newSlackMock()
+
+	defer flushStore(t)
+
+	s := &recurringScheduler{
+		eventProcessor: &eventProcessor{
+			slack:           mock,
+			channelRepo:     &channelRepo{fs},
+			expenditureRepo: &expenditureRepo{fs},
+			userRepo:        &userRepo{fs},
+		},
+		recurringRepo: &recurringRepo{fs},
+	}
+
+	if err := s.channelRepo.save(ctx, &channel{ID: "ch1", Budget: 100000}); err != nil {
+		t.Fatalf("s.channelRepo.save: %v", err)
+	}
+
+	rc := &recurring{Channel: "ch1", Amount: 1480, Category: "subscriptions", Name: "Netflix", Day: 5, StartMonth: "2026-09"}
+	if err := s.recurringRepo.add(ctx, rc); err != nil {
+		t.Fatalf("s.recurringRepo.add: %v", err)
+	}
+
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	// Runs twice to check it's recorded only once.
+	for i := 0; i < 2; i++ {
+		if err := s.materialize(ctx, now); err != nil {
+			t.Fatalf("s.materialize: %v", err)
+		}
+	}
+
+	for _, month := range []string{"2026-09", "2026-10"} {
+		exs, err := s.expenditureRepo.list(ctx, "ch1", month)
+		if err != nil {
+			t.Fatalf("s.expenditureRepo.list: %v", err)
+		}
+
+		if len(exs) != 1 || exs[0].Amount != 1480 || exs[0].Memo != "Netflix" || exs[0].Recurring != rc.ID {
+			t.Errorf("incorrect expenditures in %s: %v", month, exs)
+		}
+	}
+
+	m, _ := mock.(*slackMock)
+	if len(m.requests()) != 2 {
+		t.Errorf("incorrect replies: %d", len(m.requests()))
+	}
+
+	rcs, err := s.recurringRepo.list(ctx, "ch1")
+	if err != nil || len(rcs) != 1 || rcs[0].LastMonth != "2026-10" {
+		t.Errorf("last month should be recorded: %v, %v", rcs, err)
+	}
+
+	if err := s.recurringRepo.delete(ctx, "ch1", rc.ID); err != nil {
+		t.Fatalf("s.recurringRepo.delete: %v", err)
+	}
+
+	if err := s.recurringRepo.delete(ctx, "ch1", rc.ID); err != errNotFound {
+		t.Errorf("canceled item should not be found: %v", err)
+	}
+}
@@ -4,14 +4,29 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"time"
 
 	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
 	"google.golang.org/api/iterator"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
-const collectionName = "channels"
+const (
+	collectionName     = "channels"
+	userCollectionName = "users"
+
+	// Installations and documents of workspaces are stored under this.
+	teamCollectionName = "teams"
+
+	// Recurring items are stored under each channel.
+	recurringCollectionName = "recurring"
+
+	// Expenditures are bucketed by month in this layout.
+	monthLayout = "2006-01"
+)
 
 var errNotFound = errors.New("not found")
 
@@ -19,8 +34,11 @@ type channelRepo struct {
 	*firestore.Client
 }
 
-func (r *channelRepo) findByID(ctx context.Context, chID string) (*channel, error) {
-	docRef := r.Collection(collectionName).Doc(chID)
+func (r *channelRepo) findByID(ctx context.Context, chID string) (_ *channel, err error) {
+	ctx, done := instrumentStorage(ctx, "channels.findByID")
+	defer done(&err)
+
+	docRef := teamCollection(ctx, r.Client, collectionName).Doc(chID)
 
 	doc, err := docRef.Get(ctx)
 	if err != nil {
@@ -36,12 +54,108 @@ func (r *channelRepo) findByID(ctx context.Context, chID string) (*channel, erro
 		return nil, fmt.Errorf("doc.DataTo: %w", err)
 	}
 
+	ch.ID = chID
+
 	return &ch, nil
 }
 
-func (r *channelRepo) save(ctx context.Context, ch *channel) error {
-	docRef := r.Collection(collectionName).Doc(ch.ID)
-	if _, err := docRef.Set(ctx, ch); err != nil {
+// findByIDs returns budget channels in the IDs. IDs which are not budget channels are skipped.
+func (r *channelRepo) findByIDs(ctx context.Context, ids []string) (_ []*channel, err error) {
+	ctx, done := instrumentStorage(ctx, "channels.findByIDs")
+	defer done(&err)
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	refs := make([]*firestore.DocumentRef, 0, len(ids))
+	for _, id := range ids {
+		refs = append(refs, teamCollection(ctx, r.Client, collectionName).Doc(id))
+	}
+
+	docs, err := r.GetAll(ctx, refs)
+	if err != nil {
+		return nil, fmt.Errorf("r.GetAll: %w", err)
+	}
+
+	chs := make([]*channel, 0, len(docs))
+
+	for _, doc := range docs {
+		if !doc.Exists() {
+			continue
+		}
+
+		var ch channel
+		if err := doc.DataTo(&ch); err != nil {
+			return nil, fmt.Errorf("doc.DataTo: %w", err)
+		}
+
+		ch.ID = doc.Ref.ID
+		chs = append(chs, &ch)
+	}
+
+	return chs, nil
+}
+
+// save stores the channel with an audit entry.
+func (r *channelRepo) save(ctx context.Context, ch *channel) (err error) {
+	ctx, done := instrumentStorage(ctx, "channels.save")
+	defer done(&err)
+
+	docRef := teamCollection(ctx, r.Client, collectionName).Doc(ch.ID)
+
+	err = r.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		before, err := txData(tx, docRef)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Set(docRef, ch); err != nil {
+			return fmt.Errorf("tx.Set: %w", err)
+		}
+
+		return recordAudit(ctx, tx, r.Client, ch.ID, auditChannelSaved, "", before, ch)
+	})
+	if err != nil {
+		return fmt.Errorf("r.RunTransaction: %w", err)
+	}
+
+	return nil
+}
+
+type userRepo struct {
+	*firestore.Client
+}
+
+func (r *userRepo) findByID(ctx context.Context, userID string) (_ *user, err error) {
+	ctx, done := instrumentStorage(ctx, "users.findByID")
+	defer done(&err)
+
+	doc, err := teamCollection(ctx, r.Client, userCollectionName).Doc(userID).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, errNotFound
+		}
+
+		return nil, fmt.Errorf("r.Collection.Doc: %w", err)
+	}
+
+	var u user
+	if err := doc.DataTo(&u); err != nil {
+		return nil, fmt.Errorf("doc.DataTo: %w", err)
+	}
+
+	u.ID = userID
+
+	return &u, nil
+}
+
+func (r *userRepo) save(ctx context.Context, u *user) (err error) {
+	ctx, done := instrumentStorage(ctx, "users.save")
+	defer done(&err)
+
+	docRef := teamCollection(ctx, r.Client, userCollectionName).Doc(u.ID)
+	if _, err := docRef.Set(ctx, u); err != nil {
 		return fmt.Errorf("docRef.Set: %w", err)
 	}
 
@@ -52,27 +166,127 @@ type expenditureRepo struct {
 	*firestore.Client
 }
 
-func (r *expenditureRepo) collection(ex *expenditure) *firestore.CollectionRef {
-	month := ex.Timestamp.Format("2006-01")
+func (r *expenditureRepo) collection(ctx context.Context, ex *expenditure) *firestore.CollectionRef {
+	return r.monthCollection(ctx, ex.Channel, ex.effective().Format(monthLayout))
+}
 
-	return r.Collection(collectionName).Doc(ex.Channel).Collection(month)
+func (r *expenditureRepo) monthCollection(ctx context.Context, chID, month string) *firestore.CollectionRef {
+	return teamCollection(ctx, r.Client, collectionName).Doc(chID).Collection(month)
 }
 
-func (r *expenditureRepo) add(ctx context.Context, ex *expenditure) error {
-	docRef := r.collection(ex).Doc(ex.TS)
-	if _, err := docRef.Set(ctx, ex); err != nil {
-		return fmt.Errorf("docRef.Set: %w", err)
+// findByKey finds the expenditure by the key of expenditure.key. Deleted ones are not found.
+func (r *expenditureRepo) findByKey(ctx context.Context, chID, key string) (_ *expenditure, err error) {
+	ctx, done := instrumentStorage(ctx, "expenditures.findByKey")
+	defer done(&err)
+
+	ex, err := r.get(ctx, chID, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if ex.deleted() {
+		return nil, errNotFound
+	}
+
+	return ex, nil
+}
+
+// findDeleted finds the expenditure in the trash by the key of expenditure.key.
+func (r *expenditureRepo) findDeleted(ctx context.Context, chID, key string) (_ *expenditure, err error) {
+	ctx, done := instrumentStorage(ctx, "expenditures.findDeleted")
+	defer done(&err)
+
+	ex, err := r.get(ctx, chID, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ex.deleted() {
+		return nil, errNotFound
+	}
+
+	return ex, nil
+}
+
+// exists reports whether the expenditure of the key is recorded, including deleted ones.
+func (r *expenditureRepo) exists(ctx context.Context, chID, key string) (_ bool, err error) {
+	ctx, done := instrumentStorage(ctx, "expenditures.exists")
+	defer done(&err)
+
+	_, err = r.get(ctx, chID, key)
+	if errors.Is(err, errNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (r *expenditureRepo) get(ctx context.Context, chID, key string) (*expenditure, error) {
+	month, ts, err := parseExpenditureKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := r.monthCollection(ctx, chID, month).Doc(ts).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, errNotFound
+		}
+
+		return nil, fmt.Errorf("r.monthCollection.Doc: %w", err)
+	}
+
+	var ex expenditure
+	if err := doc.DataTo(&ex); err != nil {
+		return nil, fmt.Errorf("doc.DataTo: %w", err)
+	}
+
+	ex.Channel = chID
+	ex.TS = ts
+
+	return &ex, nil
+}
+
+// add stores the expenditure, or updates it if exists, with an audit entry.
+func (r *expenditureRepo) add(ctx context.Context, ex *expenditure) (err error) {
+	ctx, done := instrumentStorage(ctx, "expenditures.add")
+	defer done(&err)
+
+	docRef := r.collection(ctx, ex).Doc(ex.TS)
+
+	err = r.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		before, err := txData(tx, docRef)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Set(docRef, ex); err != nil {
+			return fmt.Errorf("tx.Set: %w", err)
+		}
+
+		action := auditExpenditureAdded
+		if before != nil {
+			action = auditExpenditureUpdated
+		}
+
+		return recordAudit(ctx, tx, r.Client, ex.Channel, action, ex.key(), before, ex)
+	})
+	if err != nil {
+		return fmt.Errorf("r.RunTransaction: %w", err)
 	}
 
 	return nil
 }
 
-func (r *expenditureRepo) total(ctx context.Context, ex *expenditure) (int64, error) {
-	var e *expenditure
+func (r *expenditureRepo) total(ctx context.Context, ex *expenditure) (_ int64, err error) {
+	ctx, done := instrumentStorage(ctx, "expenditures.total")
+	defer done(&err)
 
 	var total int64
 
-	docsIter := r.collection(ex).Documents(ctx)
+	docsIter := r.collection(ctx, ex).Documents(ctx)
 
 	for {
 		doc, err := docsIter.Next()
@@ -84,20 +298,534 @@ func (r *expenditureRepo) total(ctx context.Context, ex *expenditure) (int64, er
 			return -1, fmt.Errorf("docsIter.Next: %w", err)
 		}
 
+		// Decode into a new value since fields missing in the document are left unchanged.
+		var e expenditure
 		if err := doc.DataTo(&e); err != nil {
 			return -1, fmt.Errorf("doc.DataTo: %w", err)
 		}
 
+		if e.deleted() {
+			continue
+		}
+
 		total += e.Amount
 	}
 
 	return total, nil
 }
 
-func (r *expenditureRepo) delete(ctx context.Context, ex *expenditure) error {
-	docRef := r.collection(ex).Doc(ex.TS)
-	if _, err := docRef.Delete(ctx, firestore.Exists); err != nil {
-		return fmt.Errorf("docRef.Delete: %w", err)
+// list returns expenditures of the channel in the month except deleted ones.
+func (r *expenditureRepo) list(ctx context.Context, chID, month string) (_ []*expenditure, err error) {
+	ctx, done := instrumentStorage(ctx, "expenditures.list")
+	defer done(&err)
+
+	docs, err := r.monthCollection(ctx, chID, month).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("r.monthCollection.Documents.GetAll: %w", err)
+	}
+
+	exs := make([]*expenditure, 0, len(docs))
+
+	for _, doc := range docs {
+		var ex expenditure
+		if err := doc.DataTo(&ex); err != nil {
+			return nil, fmt.Errorf("doc.DataTo: %w", err)
+		}
+
+		if ex.deleted() {
+			continue
+		}
+
+		ex.Channel = chID
+		ex.TS = doc.Ref.ID
+		exs = append(exs, &ex)
+	}
+
+	return exs, nil
+}
+
+// monthTotal is the total of expenditures in a month except deleted ones.
+type monthTotal struct {
+	amount int64
+	count  int64
+}
+
+// sum returns the total of expenditures of the channel in the month, of only the category if it's not empty.
+// Amounts are summed by the server, and only deleted expenditures are read to exclude them.
+func (r *expenditureRepo) sum(ctx context.Context, chID, month, category string) (_ monthTotal, err error) {
+	ctx, done := instrumentStorage(ctx, "expenditures.sum")
+	defer done(&err)
+
+	q := r.monthCollection(ctx, chID, month).Query
+	if category != "" {
+		q = q.Where("category", "==", category)
+	}
+
+	res, err := q.NewAggregationQuery().WithCount("count").WithSum("amount", "amount").Get(ctx)
+	if err != nil {
+		return monthTotal{}, fmt.Errorf("q.NewAggregationQuery.Get: %w", err)
+	}
+
+	var t monthTotal
+
+	if t.count, err = aggregatedInt(res, "count"); err != nil {
+		return monthTotal{}, err
+	}
+
+	if t.amount, err = aggregatedInt(res, "amount"); err != nil {
+		return monthTotal{}, err
+	}
+
+	// Filtered by category here to avoid a composite index.
+	docs, err := r.monthCollection(ctx, chID, month).Where("deletedAt", ">", time.Time{}).Documents(ctx).GetAll()
+	if err != nil {
+		return monthTotal{}, fmt.Errorf("r.monthCollection.Documents.GetAll: %w", err)
+	}
+
+	for _, doc := range docs {
+		var ex expenditure
+		if err := doc.DataTo(&ex); err != nil {
+			return monthTotal{}, fmt.Errorf("doc.DataTo: %w", err)
+		}
+
+		if category == "" || ex.Category == category {
+			t.amount -= ex.Amount
+			t.count--
+		}
+	}
+
+	return t, nil
+}
+
+func aggregatedInt(res firestore.AggregationResult, alias string) (int64, error) {
+	v, ok := res[alias].(*firestorepb.Value)
+	if !ok {
+		return 0, fmt.Errorf("no %s in the aggregation result", alias)
+	}
+
+	switch v := v.GetValueType().(type) {
+	case *firestorepb.Value_IntegerValue:
+		return v.IntegerValue, nil
+	case *firestorepb.Value_DoubleValue:
+		return int64(v.DoubleValue), nil
+	case *firestorepb.Value_NullValue:
+		return 0, nil
+	}
+
+	return 0, fmt.Errorf("%s is not a number: %v", alias, v)
+}
+
+// delete moves the expenditure to the trash with an audit entry.
+// It fails with errNotFound if the expenditure doesn't exist or is already deleted.
+func (r *expenditureRepo) delete(ctx context.Context, ex *expenditure) (err error) {
+	ctx, done := instrumentStorage(ctx, "expenditures.delete")
+	defer done(&err)
+
+	docRef := r.collection(ctx, ex).Doc(ex.TS)
+	trashRef := trashCollection(ctx, r.Client, ex.Channel).Doc(trashID(ex))
+
+	err = r.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		before, err := txData(tx, docRef)
+		if err != nil {
+			return err
+		}
+
+		if before == nil || field(before, "deletedAt") != nil {
+			return errNotFound
+		}
+
+		now := time.Now()
+		by := auditSourceFrom(ctx).actor
+
+		err = tx.Update(docRef, []firestore.Update{
+			{Path: "deletedAt", Value: now},
+			{Path: "deletedBy", Value: by},
+		})
+		if err != nil {
+			return fmt.Errorf("tx.Update: %w", err)
+		}
+
+		if err := tx.Set(trashRef, &trashEntry{Ref: docRef, DeletedAt: now, DeletedBy: by}); err != nil {
+			return fmt.Errorf("tx.Set: %w", err)
+		}
+
+		after := maps.Clone(before)
+		after["deletedAt"] = now
+		after["deletedBy"] = by
+
+		return recordAudit(ctx, tx, r.Client, ex.Channel, auditExpenditureDeleted, ex.key(), before, after)
+	})
+	if err != nil {
+		return fmt.Errorf("r.RunTransaction: %w", err)
+	}
+
+	return nil
+}
+
+// restore takes the expenditure back from the trash with an audit entry.
+// It fails with errNotFound if the expenditure isn't in the trash.
+func (r *expenditureRepo) restore(ctx context.Context, ex *expenditure) (err error) {
+	ctx, done := instrumentStorage(ctx, "expenditures.restore")
+	defer done(&err)
+
+	docRef := r.collection(ctx, ex).Doc(ex.TS)
+	trashRef := trashCollection(ctx, r.Client, ex.Channel).Doc(trashID(ex))
+
+	err = r.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		before, err := txData(tx, docRef)
+		if err != nil {
+			return err
+		}
+
+		if field(before, "deletedAt") == nil {
+			return errNotFound
+		}
+
+		err = tx.Update(docRef, []firestore.Update{
+			{Path: "deletedAt", Value: firestore.Delete},
+			{Path: "deletedBy", Value: firestore.Delete},
+		})
+		if err != nil {
+			return fmt.Errorf("tx.Update: %w", err)
+		}
+
+		if err := tx.Delete(trashRef); err != nil {
+			return fmt.Errorf("tx.Delete: %w", err)
+		}
+
+		after := maps.Clone(before)
+		delete(after, "deletedAt")
+		delete(after, "deletedBy")
+
+		return recordAudit(ctx, tx, r.Client, ex.Channel, auditExpenditureRestored, ex.key(), before, after)
+	})
+	if err != nil {
+		return fmt.Errorf("r.RunTransaction: %w", err)
+	}
+
+	return nil
+}
+
+type recurringRepo struct {
+	*firestore.Client
+}
+
+func (r *recurringRepo) collection(ctx context.Context, chID string) *firestore.CollectionRef {
+	return teamCollection(ctx, r.Client, collectionName).Doc(chID).Collection(recurringCollectionName)
+}
+
+func (r *recurringRepo) add(ctx context.Context, rc *recurring) (err error) {
+	ctx, done := instrumentStorage(ctx, "recurring.add")
+	defer done(&err)
+
+	docRef := r.collection(ctx, rc.Channel).NewDoc()
+	if _, err := docRef.Create(ctx, rc); err != nil {
+		return fmt.Errorf("docRef.Create: %w", err)
+	}
+
+	rc.ID = docRef.ID
+
+	return nil
+}
+
+// list returns recurring items of the channel.
+func (r *recurringRepo) list(ctx context.Context, chID string) (_ []*recurring, err error) {
+	ctx, done := instrumentStorage(ctx, "recurring.list")
+	defer done(&err)
+
+	docs, err := r.collection(ctx, chID).OrderBy("createdAt", firestore.Asc).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("r.collection.Documents.GetAll: %w", err)
+	}
+
+	return recurringFromDocs(docs)
+}
+
+// findAll returns recurring items of all channels.
+func (r *recurringRepo) findAll(ctx context.Context) (_ []*recurring, err error) {
+	ctx, done := instrumentStorage(ctx, "recurring.findAll")
+	defer done(&err)
+
+	docs, err := r.CollectionGroup(recurringCollectionName).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("r.CollectionGroup.Documents.GetAll: %w", err)
+	}
+
+	return recurringFromDocs(docs)
+}
+
+func (r *recurringRepo) findByID(ctx context.Context, chID, id string) (_ *recurring, err error) {
+	ctx, done := instrumentStorage(ctx, "recurring.findByID")
+	defer done(&err)
+
+	doc, err := r.collection(ctx, chID).Doc(id).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, errNotFound
+		}
+
+		return nil, fmt.Errorf("r.collection.Doc.Get: %w", err)
+	}
+
+	rcs, err := recurringFromDocs([]*firestore.DocumentSnapshot{doc})
+	if err != nil {
+		return nil, err
+	}
+
+	return rcs[0], nil
+}
+
+func recurringFromDocs(docs []*firestore.DocumentSnapshot) ([]*recurring, error) {
+	rcs := make([]*recurring, 0, len(docs))
+
+	for _, doc := range docs {
+		var rc recurring
+		if err := doc.DataTo(&rc); err != nil {
+			return nil, fmt.Errorf("doc.DataTo: %w", err)
+		}
+
+		rc.ID = doc.Ref.ID
+		rc.Channel = doc.Ref.Parent.Parent.ID
+
+		// teams/{team}/channels/{channel}/recurring/{id}
+		if t := doc.Ref.Parent.Parent.Parent.Parent; t != nil {
+			rc.Team = t.ID
+		}
+		rcs = append(rcs, &rc)
+	}
+
+	return rcs, nil
+}
+
+func (r *recurringRepo) delete(ctx context.Context, chID, id string) (err error) {
+	ctx, done := instrumentStorage(ctx, "recurring.delete")
+	defer done(&err)
+
+	if _, err := r.collection(ctx, chID).Doc(id).Delete(ctx, firestore.Exists); err != nil {
+		if status.Code(err) == codes.NotFound {
+			return errNotFound
+		}
+
+		return fmt.Errorf("r.collection.Doc.Delete: %w", err)
+	}
+
+	return nil
+}
+
+// materialize records the expenditure of the month and marks the month recorded in a transaction.
+// It reports false when the month has already been recorded by another run.
+func (r *recurringRepo) materialize(
+	ctx context.Context, rc *recurring, month string, ex *expenditure,
+) (_ bool, err error) {
+	ctx, done := instrumentStorage(ctx, "recurring.materialize")
+	defer done(&err)
+
+	rcRef := r.collection(ctx, rc.Channel).Doc(rc.ID)
+	exRef := (&expenditureRepo{r.Client}).collection(ctx, ex).Doc(ex.TS)
+
+	var recorded bool
+
+	err = r.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		recorded = false
+
+		doc, err := tx.Get(rcRef)
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				return errNotFound
+			}
+
+			return fmt.Errorf("tx.Get: %w", err)
+		}
+
+		var cur recurring
+		if err := doc.DataTo(&cur); err != nil {
+			return fmt.Errorf("doc.DataTo: %w", err)
+		}
+
+		if cur.LastMonth >= month {
+			return nil
+		}
+
+		before, err := txData(tx, exRef)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Set(exRef, ex); err != nil {
+			return fmt.Errorf("tx.Set: %w", err)
+		}
+
+		if err := recordAudit(ctx, tx, r.Client, ex.Channel, auditExpenditureAdded, ex.key(), before, ex); err != nil {
+			return err
+		}
+
+		if err := tx.Update(rcRef, []firestore.Update{{Path: "lastMonth", Value: month}}); err != nil {
+			return fmt.Errorf("tx.Update: %w", err)
+		}
+
+		recorded = true
+
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			return false, errNotFound
+		}
+
+		return false, fmt.Errorf("r.RunTransaction: %w", err)
+	}
+
+	return recorded, nil
+}
+
+type installationRepo struct {
+	*firestore.Client
+	// Nil stores bot tokens in plaintext.
+	secrets *secretBox
+}
+
+func (r *installationRepo) findByID(ctx context.Context, id string) (_ *installation, err error) {
+	ctx, done := instrumentStorage(ctx, "installations.findByID")
+	defer done(&err)
+
+	doc, err := r.Collection(teamCollectionName).Doc(id).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, errNotFound
+		}
+
+		return nil, fmt.Errorf("r.Collection.Doc: %w", err)
+	}
+
+	var ins installation
+	if err := doc.DataTo(&ins); err != nil {
+		return nil, fmt.Errorf("doc.DataTo: %w", err)
+	}
+
+	ins.ID = id
+
+	if err := r.unseal(ctx, &ins); err != nil {
+		return nil, fmt.Errorf("r.unseal: %w", err)
+	}
+
+	return &ins, nil
+}
+
+// unseal decrypts the bot token into BotToken. Tokens stored in plaintext are left as they are.
+func (r *installationRepo) unseal(ctx context.Context, ins *installation) error {
+	if ins.SealedBotToken == nil {
+		return nil
+	}
+
+	if r.secrets == nil {
+		return errNoSecretKey
+	}
+
+	token, err := r.secrets.open(ctx, ins.ID, ins.SealedBotToken)
+	if err != nil {
+		return fmt.Errorf("r.secrets.open: %w", err)
+	}
+
+	ins.BotToken = token
+
+	return nil
+}
+
+// sealed returns a copy of the installation with the bot token encrypted by the primary key.
+func (r *installationRepo) sealed(ctx context.Context, ins *installation) (*installation, error) {
+	if r.secrets == nil {
+		return ins, nil
+	}
+
+	sealed, err := r.secrets.seal(ctx, ins.ID, ins.BotToken)
+	if err != nil {
+		return nil, fmt.Errorf("r.secrets.seal: %w", err)
+	}
+
+	cp := *ins
+	cp.BotToken, cp.SealedBotToken = "", sealed
+
+	return &cp, nil
+}
+
+// save stores the installation. Documents of the workspace under it are kept.
+func (r *installationRepo) save(ctx context.Context, ins *installation) (err error) {
+	ctx, done := instrumentStorage(ctx, "installations.save")
+	defer done(&err)
+
+	sealed, err := r.sealed(ctx, ins)
+	if err != nil {
+		return fmt.Errorf("r.sealed: %w", err)
+	}
+
+	docRef := r.Collection(teamCollectionName).Doc(ins.ID)
+	if _, err := docRef.Set(ctx, sealed); err != nil {
+		return fmt.Errorf("docRef.Set: %w", err)
+	}
+
+	return nil
+}
+
+// reencrypt seals bot tokens in plaintext, by old keys or without installation IDs with the primary key,
+// and returns how many are updated.
+// Installations updated meanwhile are skipped since they're sealed by the primary key.
+func (r *installationRepo) reencrypt(ctx context.Context) (n int, err error) {
+	ctx, done := instrumentStorage(ctx, "installations.reencrypt")
+	defer done(&err)
+
+	if r.secrets == nil {
+		return 0, errNoSecretKey
+	}
+
+	docs, err := r.Collection(teamCollectionName).Documents(ctx).GetAll()
+	if err != nil {
+		return 0, fmt.Errorf("r.Collection.Documents.GetAll: %w", err)
+	}
+
+	for _, doc := range docs {
+		var ins installation
+		if err := doc.DataTo(&ins); err != nil {
+			return n, fmt.Errorf("doc.DataTo(%s): %w", doc.Ref.ID, err)
+		}
+
+		ins.ID = doc.Ref.ID
+
+		if ins.BotToken == "" && (ins.SealedBotToken == nil || !r.secrets.stale(ins.SealedBotToken)) {
+			continue
+		}
+
+		if err := r.unseal(ctx, &ins); err != nil {
+			return n, fmt.Errorf("r.unseal(%s): %w", doc.Ref.ID, err)
+		}
+
+		sealed, err := r.secrets.seal(ctx, ins.ID, ins.BotToken)
+		if err != nil {
+			return n, fmt.Errorf("r.secrets.seal: %w", err)
+		}
+
+		_, err = doc.Ref.Update(ctx, []firestore.Update{
+			{Path: "botToken", Value: firestore.Delete},
+			{Path: "sealedBotToken", Value: sealed},
+		}, firestore.LastUpdateTime(doc.UpdateTime))
+		if status.Code(err) == codes.FailedPrecondition {
+			continue
+		} else if err != nil {
+			return n, fmt.Errorf("doc.Ref.Update(%s): %w", doc.Ref.ID, err)
+		}
+
+		n++
+	}
+
+	return n, nil
+}
+
+// delete removes the installation. Documents of the workspace are kept for reinstallation.
+func (r *installationRepo) delete(ctx context.Context, id string) (err error) {
+	ctx, done := instrumentStorage(ctx, "installations.delete")
+	defer done(&err)
+
+	if _, err := r.Collection(teamCollectionName).Doc(id).Delete(ctx); err != nil {
+		return fmt.Errorf("r.Collection.Doc.Delete: %w", err)
 	}
 
 	return nil
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+const dataKeySize = 32
+
+var errNoSecretKey = errors.New("secret is encrypted, but no key is configured")
+
+// keyEncrypter encrypts data encryption keys with key encryption keys which never leave it.
+// It's compatible with KMS services such as Cloud KMS, where keyID is the key version.
+type keyEncrypter interface {
+	// encrypt encrypts plaintext with the primary key and returns the ID of the key.
+	encrypt(ctx context.Context, plaintext []byte) (ciphertext []byte, keyID string, err error)
+	decrypt(ctx context.Context, keyID string, ciphertext []byte) ([]byte, error)
+	// primary returns the ID of the key which encrypts new secrets.
+	primary() string
+}
+
+// sealedSecret is a secret encrypted with its own data key, which is encrypted with a key encryption key.
+type sealedSecret struct {
+	KeyID      string `firestore:"keyId"`
+	DataKey    []byte `firestore:"dataKey"`
+	Ciphertext []byte `firestore:"ciphertext"`
+	// Whether the ID of the owner is authenticated as additional data. Secrets sealed by old versions aren't.
+	Bound bool `firestore:"bound,omitempty"`
+}
+
+// secretBox seals secrets stored in the database with envelope encryption.
+type secretBox struct {
+	keys keyEncrypter
+}
+
+// seal encrypts the secret of the owner, e.g. the installation ID, so that it can't be opened for others.
+func (b *secretBox) seal(ctx context.Context, owner, plaintext string) (*sealedSecret, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("rand.Read: %w", err)
+	}
+
+	ciphertext, err := aesGCMSeal(dataKey, []byte(plaintext), []byte(owner))
+	if err != nil {
+		return nil, fmt.Errorf("aesGCMSeal: %w", err)
+	}
+
+	encryptedKey, keyID, err := b.keys.encrypt(ctx, dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("b.keys.encrypt: %w", err)
+	}
+
+	return &sealedSecret{KeyID: keyID, DataKey: encryptedKey, Ciphertext: ciphertext, Bound: true}, nil
+}
+
+func (b *secretBox) open(ctx context.Context, owner string, s *sealedSecret) (string, error) {
+	dataKey, err := b.keys.decrypt(ctx, s.KeyID, s.DataKey)
+	if err != nil {
+		return "", fmt.Errorf("b.keys.decrypt(%s): %w", s.KeyID, err)
+	}
+
+	var ad []byte
+	if s.Bound {
+		ad = []byte(owner)
+	}
+
+	plaintext, err := aesGCMOpen(dataKey, s.Ciphertext, ad)
+	if err != nil {
+		return "", fmt.Errorf("aesGCMOpen: %w", err)
+	}
+
+	return string(plaintext), nil
+}
+
+// stale reports whether the secret should be sealed again with the primary key and the owner.
+func (b *secretBox) stale(s *sealedSecret) bool {
+	return s == nil || s.KeyID != b.keys.primary() || !s.Bound
+}
+
+// localKeyring is a keyEncrypter with AES-256 keys in a local file.
+// Keys are rotated by adding a new one as primary, and old ones are kept to decrypt existing secrets.
+type localKeyring struct {
+	Primary string `json:"primary"`
+	// Base64 encoded 32-byte keys by ID
+	Keys map[string]string `json:"keys"`
+
+	keys map[string][]byte
+}
+
+// loadKeyring loads a file like {"primary": "2", "keys": {"1": "<base64>", "2": "<base64>"}}.
+func loadKeyring(path string) (*localKeyring, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile: %w", err)
+	}
+
+	var k localKeyring
+	if err := json.Unmarshal(b, &k); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	if err := k.init(); err != nil {
+		return nil, err
+	}
+
+	return &k, nil
+}
+
+func (k *localKeyring) init() error {
+	k.keys = make(map[string][]byte, len(k.Keys))
+
+	for id, s := range k.Keys {
+		key, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return fmt.Errorf("key %s is not base64: %w", id, err)
+		}
+
+		if len(key) != dataKeySize {
+			return fmt.Errorf("key %s must be %d bytes, but %d", id, dataKeySize, len(key))
+		}
+
+		k.keys[id] = key
+	}
+
+	if _, ok := k.keys[k.Primary]; !ok {
+		return fmt.Errorf("primary key %q is not in keys", k.Primary)
+	}
+
+	return nil
+}
+
+func (k *localKeyring) encrypt(_ context.Context, plaintext []byte) ([]byte, string, error) {
+	ciphertext, err := aesGCMSeal(k.keys[k.Primary], plaintext, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return ciphertext, k.Primary, nil
+}
+
+func (k *localKeyring) decrypt(_ context.Context, keyID string, ciphertext []byte) ([]byte, error) {
+	key, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown key: %s", keyID)
+	}
+
+	return aesGCMOpen(key, ciphertext, nil)
+}
+
+func (k *localKeyring) primary() string {
+	return k.Primary
+}
+
+// aesGCMSeal encrypts plaintext and prepends the nonce. additionalData is authenticated, but not included.
+func aesGCMSeal(key, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("rand.Read: %w", err)
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func aesGCMOpen(key, ciphertext, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("aead.Open: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("aes.NewCipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("cipher.NewGCM: %w", err)
+	}
+
+	return aead, nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestKeyring(t *testing.T, primary string, ids ...string) *localKeyring {
+	t.Helper()
+
+	k := &localKeyring{Primary: primary, Keys: map[string]string{}}
+	for _, id := range ids {
+		k.Keys[id] = base64.StdEncoding.EncodeToString([]byte(strings.Repeat(id, dataKeySize)[:dataKeySize]))
+	}
+
+	if err := k.init(); err != nil {
+		t.Fatalf("k.init: %v", err)
+	}
+
+	return k
+}
+
+func Test_loadKeyring(t *testing.T) {
+	t.Parallel()
+
+	key := base64.StdEncoding.EncodeToString(make([]byte, dataKeySize))
+
+	cases := map[string]struct {
+		content string
+		wantErr bool
+	}{
+		"valid":           {content: `{"primary": "2", "keys": {"1": "` + key + `", "2": "` + key + `"}}`},
+		"unknown primary": {content: `{"primary": "3", "keys": {"1": "` + key + `"}}`, wantErr: true},
+		"short key":       {content: `{"primary": "1", "keys": {"1": "c2hvcnQ="}}`, wantErr: true},
+		"not base64":      {content: `{"primary": "1", "keys": {"1": "!"}}`, wantErr: true},
+		"not json":        {content: `primary: 1`, wantErr: true},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "keys.json")
+			if err := os.WriteFile(path, []byte(c.content), 0o600); err != nil {
+				t.Fatalf("os.WriteFile: %v", err)
+			}
+
+			if _, err := loadKeyring(path); c.wantErr != (err != nil) {
+				t.Errorf("incorrect error: %v", err)
+			}
+		})
+	}
+}
+
+func Test_secretBox(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	old := &secretBox{newTestKeyring(t, "1", "1")}
+
+	s, err := old.seal(ctx, "T0001", "xoxb-token")
+	if err != nil {
+		t.Fatalf("old.seal: %v", err)
+	}
+
+	if strings.Contains(string(s.Ciphertext), "xoxb-token") || s.KeyID != "1" || !s.Bound {
+		t.Errorf("incorrect sealed secret: %+v", s)
+	}
+
+	rotated := &secretBox{newTestKeyring(t, "2", "1", "2")}
+
+	if token, err := rotated.open(ctx, "T0001", s); err != nil || token != "xoxb-token" {
+		t.Errorf("secrets by old keys should be opened after rotation: %q, %v", token, err)
+	}
+
+	if _, err := old.open(ctx, "T0002", s); err == nil {
+		t.Errorf("secret should not be opened for other owners")
+	}
+
+	if !rotated.stale(s) || old.stale(s) {
+		t.Errorf("only secrets by old keys should be stale")
+	}
+
+	if !old.stale(&sealedSecret{KeyID: s.KeyID, DataKey: s.DataKey, Ciphertext: s.Ciphertext}) {
+		t.Errorf("secrets sealed without owners should be stale")
+	}
+
+	s.Ciphertext[len(s.Ciphertext)-1] ^= 1
+
+	if _, err := old.open(ctx, "T0001", s); err == nil {
+		t.Errorf("tampered secret should not be opened")
+	}
+
+	if _, err := (&secretBox{newTestKeyring(t, "2", "2")}).open(ctx, "T0001", s); err == nil {
+		t.Errorf("secret by removed key should not be opened")
+	}
+}
+
+func Test_installationRepo_reencrypt(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fs := getFirestoreClient(t)
+
+	defer flushStore(t)
+
+	plain := &installationRepo{fs, nil}
+	if err := plain.save(ctx, &installation{ID: "T0001", BotToken: "xoxb-1"}); err != nil {
+		t.Fatalf("plain.save: %v", err)
+	}
+
+	old := &installationRepo{fs, &secretBox{newTestKeyring(t, "1", "1")}}
+	if err := old.save(ctx, &installation{ID: "T0002", BotToken: "xoxb-2"}); err != nil {
+		t.Fatalf("old.save: %v", err)
+	}
+
+	if _, err := plain.findByID(ctx, "T0002"); !errors.Is(err, errNoSecretKey) {
+		t.Errorf("sealed token should not be read without keys: %v", err)
+	}
+
+	rotated := &installationRepo{fs, &secretBox{newTestKeyring(t, "2", "1", "2")}}
+
+	n, err := rotated.reencrypt(ctx)
+	if err != nil || n != 2 {
+		t.Fatalf("rotated.reencrypt should update 2 tokens: %d, %v", n, err)
+	}
+
+	if n, err := rotated.reencrypt(ctx); err != nil || n != 0 {
+		t.Errorf("reencrypt should be idempotent: %d, %v", n, err)
+	}
+
+	for id, token := range map[string]string{"T0001": "xoxb-1", "T0002": "xoxb-2"} {
+		doc, err := fs.Collection(teamCollectionName).Doc(id).Get(ctx)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+
+		if _, err := doc.DataAt("botToken"); err == nil {
+			t.Errorf("token of %s should not be stored in plaintext", id)
+		}
+
+		ins, err := rotated.findByID(ctx, id)
+		if err != nil || ins.BotToken != token || ins.SealedBotToken.KeyID != "2" {
+			t.Errorf("incorrect installation of %s: %+v, %v", id, ins, err)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// server runs HTTP servers and background workers until it receives a signal,
+// and then shuts them down gracefully.
+type server struct {
+	https           []*http.Server
+	shutdownTimeout time.Duration
+
+	workers []func(context.Context)
+	closers []io.Closer
+}
+
+func newServer(addr string, h http.Handler, shutdownTimeout time.Duration) *server {
+	s := &server{shutdownTimeout: shutdownTimeout}
+	s.addListener(addr, h)
+
+	return s
+}
+
+// addListener registers another HTTP server such as metrics.
+func (s *server) addListener(addr string, h http.Handler) {
+	s.https = append(s.https, &http.Server{
+		Addr:              addr,
+		Handler:           h,
+		ReadHeaderTimeout: timeoutSec * time.Second,
+	})
+}
+
+// addWorker registers a background worker which runs until ctx is done.
+func (s *server) addWorker(w func(context.Context)) {
+	s.workers = append(s.workers, w)
+}
+
+// addCloser registers a resource closed after handlers and workers finish.
+func (s *server) addCloser(c io.Closer) {
+	s.closers = append(s.closers, c)
+}
+
+func (s *server) run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	workerCtx, cancelWorkers := context.WithCancel(context.Background())
+	defer cancelWorkers()
+
+	var wg sync.WaitGroup
+
+	for _, w := range s.workers {
+		w := w
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			w(workerCtx)
+		}()
+	}
+
+	errCh := make(chan error, len(s.https))
+
+	for _, h := range s.https {
+		h := h
+
+		go func() {
+			logger.Info("listening", slog.String("addr", h.Addr))
+
+			if err := h.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errCh <- fmt.Errorf("%s: %w", h.Addr, err)
+			}
+		}()
+	}
+
+	var serveErr error
+
+	select {
+	case <-ctx.Done():
+		logger.Info("shutting down")
+	case serveErr = <-errCh:
+	}
+
+	// Stop accepting new requests and wait for in-flight requests.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	for _, h := range s.https {
+		if err := h.Shutdown(shutdownCtx); err != nil {
+			logger.Error("h.Shutdown", slog.Any("err", err))
+		}
+	}
+
+	cancelWorkers()
+
+	done := make(chan struct{})
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-shutdownCtx.Done():
+		logger.Warn("background workers didn't finish", slog.Duration("timeout", s.shutdownTimeout))
+	}
+
+	for _, c := range s.closers {
+		if err := c.Close(); err != nil {
+			logger.Error("c.Close", slog.Any("err", err))
+		}
+	}
+
+	if serveErr != nil {
+		return fmt.Errorf("ListenAndServe: %w", serveErr)
+	}
+
+	return nil
+}
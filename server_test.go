@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen: %v", err)
+	}
+	defer l.Close()
+
+	return l.Addr().String()
+}
+
+func Test_server_run_gracefulShutdown(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan struct{})
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		_, _ = w.Write([]byte("done"))
+	})
+
+	addr := freeAddr(t)
+	srv := newServer(addr, h, 5*time.Second)
+
+	workerStopped := make(chan struct{})
+	srv.addWorker(func(ctx context.Context) {
+		<-ctx.Done()
+		close(workerStopped)
+	})
+
+	closed := make(chan struct{})
+	srv.addCloser(closerFunc(func() error {
+		close(closed)
+		return nil
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+
+	go func() { runErr <- srv.run(ctx) }()
+
+	respBody := make(chan string, 1)
+
+	go func() {
+		var resp *http.Response
+
+		var err error
+
+		// Wait for the server to start listening.
+		for i := 0; i < 50; i++ {
+			resp, err = http.Get("http://" + addr)
+			if err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		if err != nil {
+			respBody <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+
+		b, _ := io.ReadAll(resp.Body)
+		respBody <- string(b)
+	}()
+
+	<-started
+	cancel()
+
+	if b := <-respBody; b != "done" {
+		t.Errorf("in-flight request should complete, but got %q", b)
+	}
+
+	if err := <-runErr; err != nil {
+		t.Errorf("srv.run: %v", err)
+	}
+
+	select {
+	case <-workerStopped:
+	default:
+		t.Error("worker should be stopped")
+	}
+
+	select {
+	case <-closed:
+	default:
+		t.Error("closer should be closed")
+	}
+}
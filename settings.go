@@ -0,0 +1,400 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type replyMode string
+
+const (
+	replyChannel replyMode = "channel"
+	replyThread  replyMode = "thread"
+	replyNone    replyMode = "none"
+)
+
+// channelSettings is settings of a channel in the config file.
+// Zero values mean unset.
+type channelSettings struct {
+	Budget     int64     `yaml:"budget"`
+	Currency   string    `yaml:"currency"`
+	Lang       string    `yaml:"lang"`
+	Timezone   string    `yaml:"timezone"`
+	Reply      replyMode `yaml:"reply"`
+	Thresholds []int     `yaml:"thresholds"`
+	Categories []string  `yaml:"categories"`
+	// Used when the channel has no rollover set by the command
+	Rollover    rolloverMode `yaml:"rollover"`
+	RolloverCap int64        `yaml:"rollover_cap"`
+}
+
+// Limits of static_select options.
+const (
+	maxCategories     = 100
+	maxCategoryLength = 75
+)
+
+var defaultCategories = []string{"food", "daily", "transport", "entertainment", "other"}
+
+// settings is the content of the config file.
+//
+//	project_id: my-project
+//	rates_file: rates.json
+//	defaults:
+//	  lang: ja
+//	  timezone: Asia/Tokyo
+//	  thresholds: [80, 100]
+//	  categories: [food, daily, transport]
+//	channels:
+//	  C0123ABCD:
+//	    budget: 100000
+//	    reply: thread
+//	    rollover: surplus
+//	    rollover_cap: 50000
+type settings struct {
+	ProjectID string                     `yaml:"project_id"`
+	RatesFile string                     `yaml:"rates_file"`
+	Defaults  channelSettings            `yaml:"defaults"`
+	Channels  map[string]channelSettings `yaml:"channels"`
+}
+
+func loadSettings(path string) (*settings, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile: %w", err)
+	}
+
+	var s settings
+
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+
+	if err := dec.Decode(&s); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	if err := s.validate(); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	return &s, nil
+}
+
+func (s *settings) validate() error {
+	var errs []string
+
+	if s.Defaults.Budget != 0 {
+		errs = append(errs, "defaults.budget: budget must be set per channel")
+	}
+
+	errs = append(errs, s.Defaults.validate("defaults")...)
+
+	ids := make([]string, 0, len(s.Channels))
+	for id := range s.Channels {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		errs = append(errs, s.Channels[id].validate("channels."+id)...)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config:\n  %s", strings.Join(errs, "\n  "))
+	}
+
+	return nil
+}
+
+func (cs channelSettings) validate(path string) []string {
+	var errs []string
+
+	if cs.Budget < 0 {
+		errs = append(errs, fmt.Sprintf("%s.budget: must not be negative: %d", path, cs.Budget))
+	}
+
+	if cs.Currency != "" && !knownCurrency(cs.Currency) {
+		errs = append(errs, fmt.Sprintf("%s.currency: must be an ISO 4217 code: %q", path, cs.Currency))
+	}
+
+	if _, ok := parseLang(cs.Lang); cs.Lang != "" && !ok {
+		errs = append(errs, fmt.Sprintf("%s.lang: must be one of %v: %q", path, langs, cs.Lang))
+	}
+
+	if _, err := time.LoadLocation(cs.Timezone); cs.Timezone != "" && err != nil {
+		errs = append(errs, fmt.Sprintf("%s.timezone: %v", path, err))
+	}
+
+	switch cs.Reply {
+	case "", replyChannel, replyThread, replyNone:
+	default:
+		errs = append(errs, fmt.Sprintf("%s.reply: must be channel, thread or none: %q", path, cs.Reply))
+	}
+
+	for _, t := range cs.Thresholds {
+		if t <= 0 {
+			errs = append(errs, fmt.Sprintf("%s.thresholds: must be positive percentages: %d", path, t))
+		}
+	}
+
+	if len(cs.Categories) > maxCategories {
+		errs = append(errs, fmt.Sprintf("%s.categories: must be at most %d", path, maxCategories))
+	}
+
+	for _, c := range cs.Categories {
+		if c == "" || len([]rune(c)) > maxCategoryLength {
+			errs = append(errs, fmt.Sprintf("%s.categories: must be 1 to %d characters: %q", path, maxCategoryLength, c))
+		}
+	}
+
+	if _, ok := parseRolloverMode(string(cs.Rollover)); cs.Rollover != "" && !ok {
+		errs = append(errs, fmt.Sprintf("%s.rollover: must be one of %v: %q", path, rolloverModes, cs.Rollover))
+	}
+
+	if cs.RolloverCap < 0 {
+		errs = append(errs, fmt.Sprintf("%s.rollover_cap: must not be negative: %d", path, cs.RolloverCap))
+	}
+
+	return errs
+}
+
+// channel returns settings of the channel merged with defaults.
+func (s *settings) channel(chID string) channelSettings {
+	cs := s.Defaults
+
+	c, ok := s.Channels[chID]
+	if !ok {
+		return cs
+	}
+
+	cs.Budget = c.Budget
+
+	if c.Currency != "" {
+		cs.Currency = c.Currency
+	}
+
+	if c.Lang != "" {
+		cs.Lang = c.Lang
+	}
+
+	if c.Timezone != "" {
+		cs.Timezone = c.Timezone
+	}
+
+	if c.Reply != "" {
+		cs.Reply = c.Reply
+	}
+
+	if c.Thresholds != nil {
+		cs.Thresholds = c.Thresholds
+	}
+
+	if c.Categories != nil {
+		cs.Categories = c.Categories
+	}
+
+	if c.Rollover != "" {
+		cs.Rollover = c.Rollover
+		cs.RolloverCap = c.RolloverCap
+	}
+
+	return cs
+}
+
+// limits returns budgets in the config file layered over base.
+func (s *settings) limits(base map[string]int64) map[string]int64 {
+	limits := make(map[string]int64, len(base)+len(s.Channels))
+	for id, budget := range base {
+		limits[id] = budget
+	}
+
+	for id, cs := range s.Channels {
+		if cs.Budget > 0 {
+			limits[id] = cs.Budget
+		}
+	}
+
+	return limits
+}
+
+// applyTo fills settings which are not set in the channel document.
+func (cs channelSettings) applyTo(ch *channel) {
+	if ch.Currency == "" {
+		ch.Currency = cs.Currency
+	}
+
+	if ch.Lang == "" {
+		ch.Lang = cs.Lang
+	}
+
+	if ch.Rollover == "" {
+		ch.Rollover = cs.Rollover
+		ch.RolloverCap = cs.RolloverCap
+	}
+}
+
+func (cs channelSettings) location() *time.Location {
+	if cs.Timezone == "" {
+		return time.Local
+	}
+
+	// Already validated
+	loc, err := time.LoadLocation(cs.Timezone)
+	if err != nil {
+		return time.Local
+	}
+
+	return loc
+}
+
+func (cs channelSettings) reply() replyMode {
+	if cs.Reply == "" {
+		return replyChannel
+	}
+
+	return cs.Reply
+}
+
+func (cs channelSettings) categories() []string {
+	if len(cs.Categories) == 0 {
+		return defaultCategories
+	}
+
+	return cs.Categories
+}
+
+// crossedThreshold returns the largest threshold in percent which total crossed
+// by the amount, or 0.
+func (cs channelSettings) crossedThreshold(budget, total, amount int64) int {
+	if budget <= 0 {
+		return 0
+	}
+
+	var crossed int
+
+	for _, t := range cs.Thresholds {
+		line := budget * int64(t)
+		if (total-amount)*100 < line && line <= total*100 && t > crossed {
+			crossed = t
+		}
+	}
+
+	return crossed
+}
+
+// settingsStore holds the current settings and replaces them on reload.
+// Requests in flight keep using the settings they got.
+type settingsStore struct {
+	path   string
+	onLoad func(context.Context, *settings) error
+
+	mu      sync.RWMutex
+	current *settings
+	modTime time.Time
+}
+
+func newSettingsStore(path string, s *settings, onLoad func(context.Context, *settings) error) *settingsStore {
+	st := &settingsStore{
+		path:    path,
+		onLoad:  onLoad,
+		current: s,
+	}
+
+	if fi, err := os.Stat(path); err == nil {
+		st.modTime = fi.ModTime()
+	}
+
+	return st
+}
+
+func (st *settingsStore) get() *settings {
+	if st == nil {
+		return &settings{}
+	}
+
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	return st.current
+}
+
+// reload loads the config file. The current settings are kept when it is invalid.
+func (st *settingsStore) reload(ctx context.Context) error {
+	s, err := loadSettings(st.path)
+	if err != nil {
+		return fmt.Errorf("loadSettings: %w", err)
+	}
+
+	if st.onLoad != nil {
+		if err := st.onLoad(ctx, s); err != nil {
+			return fmt.Errorf("st.onLoad: %w", err)
+		}
+	}
+
+	st.mu.Lock()
+	st.current = s
+	st.mu.Unlock()
+
+	logger.InfoContext(ctx, "reloaded config file", slog.String("path", st.path))
+
+	return nil
+}
+
+// watch reloads the config file on SIGHUP or when it's modified until ctx is done.
+func (st *settingsStore) watch(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		var modTime time.Time
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		case <-ticker.C:
+			fi, err := os.Stat(st.path)
+			if err != nil {
+				logger.ErrorContext(ctx, "os.Stat", slog.Any("err", err))
+
+				continue
+			}
+
+			if fi.ModTime().Equal(st.modTime) {
+				continue
+			}
+
+			modTime = fi.ModTime()
+		}
+
+		if err := st.reload(ctx); err != nil {
+			logger.ErrorContext(ctx, "failed to reload config file", slog.Any("err", err))
+
+			continue
+		}
+
+		// Invalid files are retried until they're fixed, even if the fix keeps the modification time.
+		if !modTime.IsZero() {
+			st.modTime = modTime
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeSettings(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("os.WriteFile: %v", err)
+	}
+
+	return path
+}
+
+func Test_loadSettings(t *testing.T) {
+	t.Parallel()
+
+	path := writeSettings(t, `
+project_id: my-project
+defaults:
+  lang: en
+  timezone: Asia/Tokyo
+  thresholds: [80, 100]
+channels:
+  ch1:
+    budget: 100000
+    reply: thread
+    thresholds: [50]
+`)
+
+	s, err := loadSettings(path)
+	if err != nil {
+		t.Fatalf("loadSettings: %v", err)
+	}
+
+	if s.ProjectID != "my-project" {
+		t.Errorf("project_id should be my-project, but %s", s.ProjectID)
+	}
+
+	cs := s.channel("ch1")
+	if cs.Budget != 100000 || cs.Lang != "en" || cs.reply() != replyThread || len(cs.Thresholds) != 1 {
+		t.Errorf("incorrect settings of ch1: %+v", cs)
+	}
+
+	if loc := cs.location(); loc.String() != "Asia/Tokyo" {
+		t.Errorf("location should be Asia/Tokyo, but %s", loc)
+	}
+
+	cs = s.channel("ch2")
+	if cs.Budget != 0 || cs.reply() != replyChannel || len(cs.Thresholds) != 2 {
+		t.Errorf("incorrect settings of ch2: %+v", cs)
+	}
+}
+
+func Test_loadSettings_invalid(t *testing.T) {
+	t.Parallel()
+
+	path := writeSettings(t, `
+defaults:
+  budget: 1000
+channels:
+  ch1:
+    lang: fr
+    timezone: Nowhere/City
+    reply: dm
+    thresholds: [0]
+    rollover: always
+    rollover_cap: -1
+`)
+
+	_, err := loadSettings(path)
+	if err == nil {
+		t.Fatal("loadSettings should return an error")
+	}
+
+	for _, field := range []string{
+		"defaults.budget", "channels.ch1.lang", "channels.ch1.timezone", "channels.ch1.reply", "channels.ch1.thresholds",
+		"channels.ch1.rollover", "channels.ch1.rollover_cap",
+	} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("error should contain %s: %v", field, err)
+		}
+	}
+}
+
+func Test_loadSettings_unknownField(t *testing.T) {
+	t.Parallel()
+
+	if _, err := loadSettings(writeSettings(t, "chanels: {}\n")); err == nil {
+		t.Error("loadSettings should reject unknown fields")
+	}
+}
+
+func Test_channelSettings_crossedThreshold(t *testing.T) {
+	t.Parallel()
+
+	cs := channelSettings{Thresholds: []int{50, 80, 100}}
+
+	cases := map[string]struct {
+		total, amount int64
+		e             int
+	}{
+		"below":    {total: 400, amount: 100, e: 0},
+		"cross 50": {total: 500, amount: 100, e: 50},
+		"cross 2":  {total: 900, amount: 500, e: 80},
+		"already":  {total: 900, amount: 50, e: 0},
+		"over":     {total: 1200, amount: 300, e: 100},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if a := cs.crossedThreshold(1000, c.total, c.amount); a != c.e {
+				t.Errorf("expected %d, but %d", c.e, a)
+			}
+		})
+	}
+}
+
+func Test_settingsStore_reload(t *testing.T) {
+	t.Parallel()
+
+	path := writeSettings(t, "defaults:\n  lang: en\n")
+
+	s, err := loadSettings(path)
+	if err != nil {
+		t.Fatalf("loadSettings: %v", err)
+	}
+
+	var loaded int
+
+	st := newSettingsStore(path, s, func(context.Context, *settings) error {
+		loaded++
+		return nil
+	})
+
+	if err := os.WriteFile(path, []byte("defaults:\n  lang: ja\n"), 0o600); err != nil {
+		t.Fatalf("os.WriteFile: %v", err)
+	}
+
+	if err := st.reload(context.Background()); err != nil {
+		t.Fatalf("st.reload: %v", err)
+	}
+
+	if st.get().Defaults.Lang != "ja" || loaded != 1 {
+		t.Errorf("settings should be reloaded: %+v", st.get().Defaults)
+	}
+
+	if err := os.WriteFile(path, []byte("defaults:\n  lang: fr\n"), 0o600); err != nil {
+		t.Fatalf("os.WriteFile: %v", err)
+	}
+
+	if err := st.reload(context.Background()); err == nil {
+		t.Error("st.reload should return an error")
+	}
+
+	if st.get().Defaults.Lang != "ja" {
+		t.Errorf("invalid settings should not be applied: %+v", st.get().Defaults)
+	}
+}
+
+func Test_settingsStore_watch(t *testing.T) {
+	t.Parallel()
+
+	path := writeSettings(t, "defaults:\n  lang: en\n")
+
+	s, err := loadSettings(path)
+	if err != nil {
+		t.Fatalf("loadSettings: %v", err)
+	}
+
+	st := newSettingsStore(path, s, nil)
+
+	mtime := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	write := func(content string) {
+		t.Helper()
+
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("os.WriteFile: %v", err)
+		}
+
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatalf("os.Chtimes: %v", err)
+		}
+	}
+
+	write("defaults:\n  lang: fr\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go st.watch(ctx, 10*time.Millisecond)
+
+	time.Sleep(50 * time.Millisecond)
+
+	// Fixed without changing the modification time
+	write("defaults:\n  lang: ja\n")
+
+	for i := 0; i < 100 && st.get().Defaults.Lang != "ja"; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if st.get().Defaults.Lang != "ja" {
+		t.Errorf("fixed settings should be reloaded: %+v", st.get().Defaults)
+	}
+}
@@ -0,0 +1,28 @@
+package slack
+
+import (
+	"context"
+)
+
+// AuthTestRes is a response of auth.test method.
+// https://api.slack.com/methods/auth.test
+type AuthTestRes struct {
+	apiResponse
+	URL    string `json:"url"`
+	Team   string `json:"team"`
+	User   string `json:"user"`
+	TeamID string `json:"team_id"`
+	UserID string `json:"user_id"`
+	BotID  string `json:"bot_id"`
+}
+
+// AuthTest checks the token.
+func (c *client) AuthTest(ctx context.Context) (*AuthTestRes, error) {
+	var res AuthTestRes
+
+	if err := c.post(ctx, "auth.test", struct{}{}, &res); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
@@ -0,0 +1,95 @@
+package slack
+
+// Block types and element types of Block Kit.
+// https://api.slack.com/reference/block-kit
+const (
+	BlockSection = "section"
+	BlockActions = "actions"
+	BlockInput   = "input"
+	BlockContext = "context"
+	BlockDivider = "divider"
+	BlockHeader  = "header"
+
+	ElementButton         = "button"
+	ElementStaticSelect   = "static_select"
+	ElementPlainTextInput = "plain_text_input"
+	ElementDatePicker     = "datepicker"
+	ElementUsersSelect    = "users_select"
+
+	ElementConversationsSelect = "conversations_select"
+
+	TextPlain    = "plain_text"
+	TextMarkdown = "mrkdwn"
+
+	StylePrimary = "primary"
+	StyleDanger  = "danger"
+
+	ViewModal = "modal"
+	ViewHome  = "home"
+)
+
+// Block is a layout block. Only fields of its type are set.
+type Block struct {
+	Type      string   `json:"type"`
+	BlockID   string   `json:"block_id,omitempty"`
+	Text      *Text    `json:"text,omitempty"`
+	Fields    []*Text  `json:"fields,omitempty"`
+	Accessory *Element `json:"accessory,omitempty"`
+	// *Element in actions blocks and *Text in context blocks
+	Elements []interface{} `json:"elements,omitempty"`
+	Label    *Text         `json:"label,omitempty"`
+	Element  *Element      `json:"element,omitempty"`
+	Optional bool          `json:"optional,omitempty"`
+}
+
+// Element is a block element. Only fields of its type are set.
+type Element struct {
+	Type          string          `json:"type"`
+	ActionID      string          `json:"action_id,omitempty"`
+	Text          *Text           `json:"text,omitempty"`
+	Value         string          `json:"value,omitempty"`
+	Style         string          `json:"style,omitempty"`
+	Placeholder   *Text           `json:"placeholder,omitempty"`
+	Options       []*OptionObject `json:"options,omitempty"`
+	InitialOption *OptionObject   `json:"initial_option,omitempty"`
+	InitialValue  string          `json:"initial_value,omitempty"`
+	InitialDate   string          `json:"initial_date,omitempty"`
+	InitialUser   string          `json:"initial_user,omitempty"`
+	// Preselects the channel where a modal is opened from in conversations_select.
+	DefaultToCurrentConversation bool `json:"default_to_current_conversation,omitempty"`
+}
+
+// Text is a text object.
+type Text struct {
+	Type  string `json:"type"`
+	Text  string `json:"text"`
+	Emoji bool   `json:"emoji,omitempty"`
+}
+
+// OptionObject is an option of select menus.
+type OptionObject struct {
+	Text  *Text  `json:"text"`
+	Value string `json:"value"`
+}
+
+// PlainText builds a plain_text object.
+func PlainText(s string) *Text {
+	return &Text{Type: TextPlain, Text: s}
+}
+
+// Markdown builds a mrkdwn object.
+func Markdown(s string) *Text {
+	return &Text{Type: TextMarkdown, Text: s}
+}
+
+// View is a modal or a home tab.
+// https://api.slack.com/reference/surfaces/views
+type View struct {
+	Type            string   `json:"type"`
+	CallbackID      string   `json:"callback_id,omitempty"`
+	PrivateMetadata string   `json:"private_metadata,omitempty"`
+	Title           *Text    `json:"title,omitempty"`
+	Submit          *Text    `json:"submit,omitempty"`
+	Close           *Text    `json:"close,omitempty"`
+	Blocks          []*Block `json:"blocks"`
+}
@@ -0,0 +1,24 @@
+package slack
+
+import (
+	"context"
+)
+
+// ChatPostEphemeralReq is a request for chat.postEphemeral method.
+// https://api.slack.com/methods/chat.postEphemeral
+type ChatPostEphemeralReq struct {
+	Channel  string `json:"channel"`
+	User     string `json:"user"`
+	Text     string `json:"text"`
+	ThreadTS string `json:"thread_ts,omitempty"`
+}
+
+type chatPostEphemeralRes struct {
+	apiResponse
+}
+
+func (c *client) ChatPostEphemeral(ctx context.Context, r *ChatPostEphemeralReq) error {
+	var res chatPostEphemeralRes
+
+	return c.post(ctx, "chat.postEphemeral", r, &res)
+}
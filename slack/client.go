@@ -1,24 +1,304 @@
 package slack
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/rand"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/xerrors"
+)
+
+const (
+	defaultBaseURL    = "https://slack.com/api/"
+	defaultTimeout    = 10 * time.Second
+	defaultMaxRetries = 3
+	defaultMinBackoff = 500 * time.Millisecond
+	defaultMaxBackoff = 8 * time.Second
+	// Slack expects responses to events, commands and interactions in 3 seconds.
+	defaultMaxRetryTime = 2 * time.Second
+
+	tracerName = "github.com/nownabe/moneysaver/slack"
 )
 
 // Client is an interface of Slack Client.
 type Client interface {
+	AuthTest(context.Context) (*AuthTestRes, error)
 	ChatPostMessage(context.Context, *ChatPostMessageReq) error
+	ChatPostEphemeral(context.Context, *ChatPostEphemeralReq) error
+	ViewsOpen(context.Context, *ViewsOpenReq) error
+	ViewsPublish(context.Context, *ViewsPublishReq) error
+	UsersConversations(ctx context.Context, userID string) ([]*Conversation, error)
+}
+
+// Observer is called after each Web API call with the method, latency and error.
+type Observer func(method string, d time.Duration, err error)
+
+// Option configures the client.
+type Option func(*client)
+
+// WithObserver sets an observer of Web API calls, e.g. for metrics.
+func WithObserver(o Observer) Option {
+	return func(c *client) {
+		c.observer = o
+	}
+}
+
+// WithBaseURL sets the base URL of Web API, e.g. for a stand-in server in tests.
+func WithBaseURL(u string) Option {
+	return func(c *client) {
+		c.baseURL = strings.TrimSuffix(u, "/") + "/"
+	}
+}
+
+// WithTimeout sets the timeout of each attempt.
+func WithTimeout(d time.Duration) Option {
+	return func(c *client) {
+		c.timeout = d
+	}
+}
+
+// WithRetry sets the max number of retries and the range of exponential backoff.
+func WithRetry(maxRetries int, minBackoff, maxBackoff time.Duration) Option {
+	return func(c *client) {
+		c.maxRetries = maxRetries
+		c.minBackoff = minBackoff
+		c.maxBackoff = maxBackoff
+	}
+}
+
+// WithMaxRetryTime sets how long a call can take including retries.
+// Retries which wouldn't start by then or by the deadline of the context are given up.
+func WithMaxRetryTime(d time.Duration) Option {
+	return func(c *client) {
+		c.maxRetryTime = d
+	}
 }
 
 type client struct {
-	token  string
-	client *http.Client
+	token    string
+	client   *http.Client
+	observer Observer
+
+	baseURL      string
+	timeout      time.Duration
+	maxRetries   int
+	minBackoff   time.Duration
+	maxBackoff   time.Duration
+	maxRetryTime time.Duration
+	now          func() time.Time
+	sleep        func(context.Context, time.Duration) error
 }
 
 // New builds a new slack client.
-func New(token string) Client {
-	return &client{
-		token:  token,
-		client: &http.Client{},
+func New(token string, opts ...Option) Client {
+	return newClient(token, opts...)
+}
+
+func newClient(token string, opts ...Option) *client {
+	c := &client{
+		token:        token,
+		client:       &http.Client{},
+		baseURL:      defaultBaseURL,
+		timeout:      defaultTimeout,
+		maxRetries:   defaultMaxRetries,
+		minBackoff:   defaultMinBackoff,
+		maxBackoff:   defaultMaxBackoff,
+		maxRetryTime: defaultMaxRetryTime,
+		now:          time.Now,
+		sleep:        sleep,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+type apiResponse struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error"`
+}
+
+func (r *apiResponse) result() *apiResponse {
+	return r
+}
+
+type response interface {
+	result() *apiResponse
+}
+
+// post calls the Web API method with JSON body and decodes the response into res.
+func (c *client) post(ctx context.Context, method string, r interface{}, res response) error {
+	reqBody, err := json.Marshal(r)
+	if err != nil {
+		return xerrors.Errorf("failed to marshal slack %s request: %w", method, err)
+	}
+
+	return c.call(ctx, method, "application/json", reqBody, res)
+}
+
+// postForm calls the Web API method with form body, which is required by some read methods.
+func (c *client) postForm(ctx context.Context, method string, values url.Values, res response) error {
+	return c.call(ctx, method, "application/x-www-form-urlencoded", []byte(values.Encode()), res)
+}
+
+// call calls the Web API method with retries and decodes the response into res.
+func (c *client) call(ctx context.Context, method, contentType string, reqBody []byte, res response) (err error) {
+	if c.observer != nil {
+		defer func(start time.Time) { c.observer(method, time.Since(start), err) }(time.Now())
+	}
+
+	// Trace context is not propagated to slack.com, which is outside of our traces.
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "slack."+method, trace.WithSpanKind(trace.SpanKindClient))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	deadline := c.now().Add(c.maxRetryTime)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := c.do(ctx, method, contentType, reqBody, res)
+		if err == nil {
+			return nil
+		}
+
+		if !retryable(err) || attempt >= c.maxRetries || ctx.Err() != nil {
+			return err
+		}
+
+		wait := retryAfter
+		if wait == 0 {
+			wait = c.backoff(attempt)
+		}
+
+		// Don't hold the request which is waiting for the response.
+		if c.now().Add(wait).After(deadline) {
+			span.AddEvent("retry given up", trace.WithAttributes(attribute.String("wait", wait.String())))
+			return err
+		}
+
+		span.AddEvent("retry", trace.WithAttributes(
+			attribute.Int("attempt", attempt+1), attribute.String("wait", wait.String())))
+
+		if err := c.sleep(ctx, wait); err != nil {
+			return xerrors.Errorf("gave up retrying slack %s: %w", method, err)
+		}
+	}
+}
+
+// do makes a single attempt and returns Retry-After if Slack requests it.
+func (c *client) do(ctx context.Context, method, contentType string, reqBody []byte, res response) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+method, bytes.NewReader(reqBody))
+	if err != nil {
+		return 0, xerrors.Errorf("failed to build http request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, &transportError{xerrors.Errorf("failed to send request: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return retryAfter, &transportError{xerrors.Errorf("failed to read response body: %w", err)}
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return retryAfter, &StatusError{
+			Method:     method,
+			StatusCode: resp.StatusCode,
+			Body:       string(body),
+			RetryAfter: retryAfter,
+		}
+	}
+
+	if err := json.Unmarshal(body, res); err != nil {
+		return retryAfter, xerrors.Errorf("failed to unmarshal response body: %w", err)
+	}
+
+	if r := res.result(); !r.OK {
+		return retryAfter, &APIError{Method: method, Code: r.Error}
+	}
+
+	return 0, nil
+}
+
+// retryable reports whether err is transient.
+// Note that chat.postMessage may be duplicated when a request reached Slack but its response didn't.
+func retryable(err error) bool {
+	var (
+		apiErr       *APIError
+		statusErr    *StatusError
+		transportErr *transportError
+	)
+
+	switch {
+	case errors.As(err, &apiErr):
+		return transientCodes[apiErr.Code]
+	case errors.As(err, &statusErr):
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= http.StatusInternalServerError
+	case errors.As(err, &transportErr):
+		return true
+	}
+
+	return false
+}
+
+func (c *client) backoff(attempt int) time.Duration {
+	return backoff(c.minBackoff, c.maxBackoff, attempt)
+}
+
+// backoff returns exponential backoff with jitter in [d/2, d), where d is doubled from minD up to maxD.
+func backoff(minD, maxD time.Duration, attempt int) time.Duration {
+	d := maxD
+	if attempt < 32 && minD<<attempt < maxD {
+		d = minD << attempt
+	}
+
+	if d < 2 {
+		return d
+	}
+
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
 	}
 }
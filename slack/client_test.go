@@ -0,0 +1,256 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type stubResponse struct {
+	status     int
+	retryAfter string
+	body       string
+	delay      time.Duration
+}
+
+// stubServer responds in order and repeats the last response.
+func stubServer(t *testing.T, responses ...stubResponse) (*httptest.Server, func() int) {
+	t.Helper()
+
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		res := responses[len(responses)-1]
+		if calls < len(responses) {
+			res = responses[calls]
+		}
+		calls++
+		mu.Unlock()
+
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("incorrect Authorization header: %s", r.Header.Get("Authorization"))
+		}
+
+		time.Sleep(res.delay)
+
+		if res.retryAfter != "" {
+			w.Header().Set("Retry-After", res.retryAfter)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(res.status)
+		_, _ = w.Write([]byte(res.body))
+	}))
+
+	t.Cleanup(srv.Close)
+
+	return srv, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return calls
+	}
+}
+
+func newTestClient(srv *httptest.Server, waits *[]time.Duration) *client {
+	c, _ := New("token",
+		WithBaseURL(srv.URL),
+		WithTimeout(100*time.Millisecond),
+		WithRetry(2, time.Millisecond, 4*time.Millisecond),
+		WithMaxRetryTime(time.Minute),
+	).(*client)
+
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		*waits = append(*waits, d)
+		return nil
+	}
+
+	return c
+}
+
+func TestClient_ChatPostMessage(t *testing.T) {
+	t.Parallel()
+
+	ok := stubResponse{status: http.StatusOK, body: `{"ok":true}`}
+
+	cases := map[string]struct {
+		responses []stubResponse
+		calls     int
+		err       error
+		waits     []time.Duration
+	}{
+		"success": {
+			responses: []stubResponse{ok},
+			calls:     1,
+		},
+		"retry after": {
+			responses: []stubResponse{{status: http.StatusTooManyRequests, retryAfter: "3"}, ok},
+			calls:     2,
+			waits:     []time.Duration{3 * time.Second},
+		},
+		"ratelimited in body": {
+			responses: []stubResponse{{status: http.StatusOK, retryAfter: "1", body: `{"ok":false,"error":"ratelimited"}`}, ok},
+			calls:     2,
+			waits:     []time.Duration{time.Second},
+		},
+		"server error": {
+			responses: []stubResponse{{status: http.StatusServiceUnavailable}, ok},
+			calls:     2,
+		},
+		"timeout": {
+			responses: []stubResponse{{status: http.StatusOK, body: `{"ok":true}`, delay: 300 * time.Millisecond}, ok},
+			calls:     2,
+		},
+		"rate limited": {
+			responses: []stubResponse{{status: http.StatusTooManyRequests, retryAfter: "1"}},
+			calls:     3,
+			err:       ErrRateLimited,
+			waits:     []time.Duration{time.Second, time.Second},
+		},
+		"channel not found": {
+			responses: []stubResponse{{status: http.StatusOK, body: `{"ok":false,"error":"channel_not_found"}`}},
+			calls:     1,
+			err:       ErrChannelNotFound,
+		},
+		"not in channel": {
+			responses: []stubResponse{{status: http.StatusOK, body: `{"ok":false,"error":"not_in_channel"}`}},
+			calls:     1,
+			err:       ErrNotInChannel,
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			srv, calls := stubServer(t, c.responses...)
+
+			var waits []time.Duration
+
+			err := newTestClient(srv, &waits).ChatPostMessage(context.Background(), &ChatPostMessageReq{Channel: "C0001"})
+
+			if c.err == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			} else if c.err != nil && !errors.Is(err, c.err) {
+				t.Fatalf("error should be %v, but %v", c.err, err)
+			}
+
+			if a := calls(); a != c.calls {
+				t.Errorf("calls should be %d, but %d", c.calls, a)
+			}
+
+			if c.waits != nil {
+				if len(waits) != len(c.waits) {
+					t.Fatalf("waits should be %v, but %v", c.waits, waits)
+				}
+
+				for i := range waits {
+					if waits[i] != c.waits[i] {
+						t.Errorf("waits should be %v, but %v", c.waits, waits)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestClient_maxRetryTime(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		retryAfter string
+		timeout    time.Duration
+		calls      int
+	}{
+		"in time":         {retryAfter: "1", calls: 2},
+		"after max":       {retryAfter: "3", calls: 1},
+		"after deadline":  {retryAfter: "1", timeout: 500 * time.Millisecond, calls: 1},
+		"before deadline": {retryAfter: "1", timeout: 5 * time.Second, calls: 2},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			srv, calls := stubServer(t,
+				stubResponse{status: http.StatusTooManyRequests, retryAfter: c.retryAfter},
+				stubResponse{status: http.StatusOK, body: `{"ok":true}`},
+			)
+
+			var waits []time.Duration
+
+			sc := newTestClient(srv, &waits)
+			sc.maxRetryTime = 2 * time.Second
+
+			ctx := context.Background()
+			if c.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, c.timeout)
+				defer cancel()
+			}
+
+			err := sc.ChatPostMessage(ctx, &ChatPostMessageReq{Channel: "C0001"})
+			if c.calls == 1 && !errors.Is(err, ErrRateLimited) {
+				t.Errorf("given up retry should fail with the last error: %v", err)
+			} else if c.calls == 2 && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if a := calls(); a != c.calls {
+				t.Errorf("calls should be %d, but %d", c.calls, a)
+			}
+		})
+	}
+}
+
+func TestClient_backoff(t *testing.T) {
+	t.Parallel()
+
+	c := &client{minBackoff: 100 * time.Millisecond, maxBackoff: time.Second}
+
+	for attempt, max := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		max *= time.Millisecond
+
+		for i := 0; i < 10; i++ {
+			if d := c.backoff(attempt); d < max/2 || d >= max {
+				t.Errorf("backoff of attempt %d should be in [%s, %s), but %s", attempt, max/2, max, d)
+			}
+		}
+	}
+}
+
+func TestClient_UsersConversations(t *testing.T) {
+	t.Parallel()
+
+	srv, calls := stubServer(t,
+		stubResponse{status: http.StatusOK, body: `{"ok":true,"channels":[{"id":"C1","name":"one"}],"response_metadata":{"next_cursor":"next"}}`},
+		stubResponse{status: http.StatusOK, body: `{"ok":true,"channels":[{"id":"C2","name":"two"}],"response_metadata":{"next_cursor":""}}`},
+	)
+
+	var waits []time.Duration
+
+	convs, err := newTestClient(srv, &waits).UsersConversations(context.Background(), "U1")
+	if err != nil {
+		t.Fatalf("UsersConversations: %v", err)
+	}
+
+	if len(convs) != 2 || convs[0].ID != "C1" || convs[1].Name != "two" {
+		t.Errorf("incorrect conversations: %v", convs)
+	}
+
+	if calls() != 2 {
+		t.Errorf("should follow the cursor: %d calls", calls())
+	}
+}
@@ -0,0 +1,108 @@
+package slack
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrRateLimited is returned when the method is rate limited even after retries.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrChannelNotFound is returned when the channel doesn't exist or the bot can't see it.
+	ErrChannelNotFound = errors.New("channel not found")
+	// ErrNotInChannel is returned when the bot is not a member of the channel.
+	ErrNotInChannel = errors.New("not in channel")
+	// ErrInvalidAuth is returned when the token is invalid, revoked or expired, which retries don't fix.
+	ErrInvalidAuth = errors.New("invalid auth")
+)
+
+// Error codes of tokens which are not accepted.
+var authCodes = map[string]bool{
+	"invalid_auth":     true,
+	"not_authed":       true,
+	"account_inactive": true,
+	"token_revoked":    true,
+	"token_expired":    true,
+}
+
+// Error codes which may succeed on retry.
+// https://api.slack.com/methods/chat.postMessage#errors
+var transientCodes = map[string]bool{
+	"ratelimited":         true,
+	"internal_error":      true,
+	"fatal_error":         true,
+	"service_unavailable": true,
+	"request_timeout":     true,
+}
+
+// APIError is an error returned by Slack Web API with ok: false.
+type APIError struct {
+	Method string
+	Code   string
+}
+
+func (e *APIError) Error() string {
+	return "slack " + e.Method + " returned an error: " + e.Code
+}
+
+// Unwrap maps well-known error codes to the sentinel errors so that errors.Is works.
+func (e *APIError) Unwrap() error {
+	switch e.Code {
+	case "ratelimited":
+		return ErrRateLimited
+	case "channel_not_found":
+		return ErrChannelNotFound
+	case "not_in_channel":
+		return ErrNotInChannel
+	}
+
+	if authCodes[e.Code] {
+		return ErrInvalidAuth
+	}
+
+	return nil
+}
+
+// StatusError is an error of HTTP status code from Slack.
+type StatusError struct {
+	Method     string
+	StatusCode int
+	Body       string
+	// Parsed Retry-After header
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return "slack " + e.Method + " failed with status code " + strconv.Itoa(e.StatusCode) + " (" + e.Body + ")"
+}
+
+func (e *StatusError) Unwrap() error {
+	if e.StatusCode == 429 {
+		return ErrRateLimited
+	}
+
+	return nil
+}
+
+// transportError is a network error such as a timeout of an attempt.
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transportError) Unwrap() error {
+	return e.err
+}
+
+func parseRetryAfter(v string) time.Duration {
+	sec, err := strconv.Atoi(v)
+	if err != nil || sec < 0 {
+		return 0
+	}
+
+	return time.Duration(sec) * time.Second
+}
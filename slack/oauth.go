@@ -0,0 +1,56 @@
+package slack
+
+import (
+	"context"
+	"net/url"
+)
+
+// OAuthV2AccessRes is a response of oauth.v2.access method.
+// https://api.slack.com/methods/oauth.v2.access
+type OAuthV2AccessRes struct {
+	apiResponse
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	Scope       string `json:"scope"`
+	BotUserID   string `json:"bot_user_id"`
+	AppID       string `json:"app_id"`
+	Team        struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"team"`
+	// Nil unless the workspace is in an Enterprise Grid organization
+	Enterprise *struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"enterprise"`
+	IsEnterpriseInstall bool `json:"is_enterprise_install"`
+	AuthedUser          struct {
+		ID string `json:"id"`
+	} `json:"authed_user"`
+}
+
+// OAuthV2Access exchanges the code of OAuth v2 flow for a bot token.
+// It authenticates with the client ID and secret instead of a token.
+func OAuthV2Access(
+	ctx context.Context, clientID, clientSecret, code, redirectURI string, opts ...Option,
+) (*OAuthV2AccessRes, error) {
+	c := newClient("", opts...)
+
+	values := url.Values{
+		"client_id":     {clientID},
+		"client_secret": {clientSecret},
+		"code":          {code},
+	}
+
+	if redirectURI != "" {
+		values.Set("redirect_uri", redirectURI)
+	}
+
+	var res OAuthV2AccessRes
+
+	if err := c.postForm(ctx, "oauth.v2.access", values, &res); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
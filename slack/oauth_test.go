@@ -0,0 +1,43 @@
+package slack
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOAuthV2Access(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/oauth.v2.access" {
+			t.Errorf("incorrect path: %s", r.URL.Path)
+		}
+
+		if a := r.Header.Get("Authorization"); a != "" {
+			t.Errorf("Authorization header should not be sent: %s", a)
+		}
+
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("r.ParseForm: %v", err)
+		}
+
+		if r.PostForm.Get("client_id") != "id" || r.PostForm.Get("client_secret") != "secret" || r.PostForm.Get("code") != "code" {
+			t.Errorf("incorrect form: %v", r.PostForm)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true,"access_token":"xoxb-1","bot_user_id":"U1","team":{"id":"T1","name":"team"}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	res, err := OAuthV2Access(context.Background(), "id", "secret", "code", "", WithBaseURL(srv.URL))
+	if err != nil {
+		t.Fatalf("OAuthV2Access: %v", err)
+	}
+
+	if res.AccessToken != "xoxb-1" || res.Team.ID != "T1" || res.BotUserID != "U1" || res.Enterprise != nil {
+		t.Errorf("incorrect response: %+v", res)
+	}
+}
@@ -0,0 +1,180 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/xerrors"
+)
+
+// Envelope types of Socket Mode.
+// https://api.slack.com/apis/connections/socket-implement
+const (
+	EnvelopeHello         = "hello"
+	EnvelopeDisconnect    = "disconnect"
+	EnvelopeEventsAPI     = "events_api"
+	EnvelopeSlashCommands = "slash_commands"
+	EnvelopeInteractive   = "interactive"
+)
+
+// Range of backoff before reconnecting after errors
+const (
+	socketModeMinBackoff = time.Second
+	socketModeMaxBackoff = time.Minute
+)
+
+// Envelope is a message received over Socket Mode.
+type Envelope struct {
+	Type                   string          `json:"type"`
+	EnvelopeID             string          `json:"envelope_id"`
+	Payload                json.RawMessage `json:"payload"`
+	AcceptsResponsePayload bool            `json:"accepts_response_payload"`
+	RetryAttempt           int             `json:"retry_attempt"`
+	Reason                 string          `json:"reason"`
+}
+
+type ack struct {
+	EnvelopeID string      `json:"envelope_id"`
+	Payload    interface{} `json:"payload,omitempty"`
+}
+
+// EnvelopeHandler handles an envelope and returns the payload of the acknowledgement, which may be nil.
+type EnvelopeHandler func(context.Context, *Envelope) (interface{}, error)
+
+type appsConnectionsOpenRes struct {
+	apiResponse
+	URL string `json:"url"`
+}
+
+// SocketMode receives events, slash commands and interactions over WebSocket
+// instead of HTTP endpoints.
+type SocketMode struct {
+	api     *client
+	dialer  *websocket.Dialer
+	onError func(error)
+}
+
+// NewSocketMode builds a Socket Mode client with an app-level token (xapp-).
+func NewSocketMode(appToken string, opts ...Option) *SocketMode {
+	api := newClient(appToken, opts...)
+
+	return &SocketMode{
+		api:    api,
+		dialer: websocket.DefaultDialer,
+	}
+}
+
+// OnError sets a function called with errors of connections and acknowledgements, e.g. to log them.
+func (s *SocketMode) OnError(f func(error)) {
+	s.onError = f
+}
+
+func (s *SocketMode) handleError(err error) {
+	if s.onError != nil {
+		s.onError(err)
+	}
+}
+
+// Run receives envelopes until ctx is done, reconnecting when Slack asks to or with backoff after errors.
+// It returns an error only when the app token is not accepted.
+// It waits for handlers in flight before returning.
+func (s *SocketMode) Run(ctx context.Context, h EnvelopeHandler) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for attempt := 0; ; attempt++ {
+		connected, err := s.connect(ctx, h, &wg)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if errors.Is(err, ErrInvalidAuth) {
+			return err
+		}
+
+		if !errors.Is(err, errDisconnect) {
+			s.handleError(err)
+		}
+
+		// Backoff grows only while connections fail.
+		if connected {
+			attempt = 0
+		}
+
+		if err := s.api.sleep(ctx, backoff(socketModeMinBackoff, socketModeMaxBackoff, attempt)); err != nil {
+			return nil
+		}
+	}
+}
+
+var errDisconnect = errors.New("disconnect requested")
+
+// connect receives envelopes until the connection is closed, and reports whether it was connected.
+func (s *SocketMode) connect(ctx context.Context, h EnvelopeHandler, wg *sync.WaitGroup) (bool, error) {
+	var res appsConnectionsOpenRes
+	if err := s.api.post(ctx, "apps.connections.open", struct{}{}, &res); err != nil {
+		return false, xerrors.Errorf("failed to open connection: %w", err)
+	}
+
+	conn, resp, err := s.dialer.DialContext(ctx, res.URL, nil)
+	if err != nil {
+		return false, xerrors.Errorf("failed to dial %s: %w", res.URL, err)
+	}
+	defer conn.Close()
+
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+
+	// Unblock ReadJSON on shutdown.
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+
+	var writeMu sync.Mutex
+
+	for {
+		var env Envelope
+		if err := conn.ReadJSON(&env); err != nil {
+			return true, xerrors.Errorf("failed to read envelope: %w", err)
+		}
+
+		switch env.Type {
+		case EnvelopeHello:
+			continue
+		case EnvelopeDisconnect:
+			return true, xerrors.Errorf("%s: %w", env.Reason, errDisconnect)
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			// Handlers outlive the connection so that they aren't canceled on reconnect.
+			payload, err := h(context.WithoutCancel(ctx), &env)
+			if err != nil {
+				// Slack retries envelopes which are not acknowledged.
+				return
+			}
+
+			writeMu.Lock()
+			defer writeMu.Unlock()
+
+			if err := conn.WriteJSON(&ack{EnvelopeID: env.EnvelopeID, Payload: payload}); err != nil {
+				s.handleError(xerrors.Errorf("failed to acknowledge %s: %w", env.EnvelopeID, err))
+			}
+		}()
+	}
+}
@@ -0,0 +1,271 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeSocketServer serves apps.connections.open and a WebSocket endpoint.
+// Each connection sends hello and the envelopes of the session, waits for their acks,
+// and then sends disconnect unless it's the last session.
+func fakeSocketServer(t *testing.T, sessions [][]Envelope) (*httptest.Server, <-chan ack) {
+	t.Helper()
+
+	var (
+		mu    sync.Mutex
+		conns int
+	)
+
+	acks := make(chan ack, 10)
+	upgrader := websocket.Upgrader{}
+	mux := http.NewServeMux()
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	mux.HandleFunc("/apps.connections.open", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xapp-token" {
+			t.Errorf("incorrect Authorization header: %s", r.Header.Get("Authorization"))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true,"url":"ws` + strings.TrimPrefix(srv.URL, "http") + `/link"}`))
+	})
+
+	mux.HandleFunc("/link", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("failed to upgrade: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		mu.Lock()
+		i := conns
+		conns++
+		mu.Unlock()
+
+		if i >= len(sessions) {
+			return
+		}
+
+		if err := conn.WriteJSON(&Envelope{Type: EnvelopeHello}); err != nil {
+			t.Errorf("failed to write hello: %v", err)
+			return
+		}
+
+		for _, env := range sessions[i] {
+			if err := conn.WriteJSON(&env); err != nil {
+				t.Errorf("failed to write envelope: %v", err)
+				return
+			}
+		}
+
+		for range sessions[i] {
+			// Unacknowledged envelopes are left until the client closes the connection.
+			var a ack
+			if err := conn.ReadJSON(&a); err != nil {
+				return
+			}
+			acks <- a
+		}
+
+		if i < len(sessions)-1 {
+			_ = conn.WriteJSON(&Envelope{Type: EnvelopeDisconnect, Reason: "refresh_requested"})
+			return
+		}
+
+		// Keep the last connection until the client closes it.
+		_, _, _ = conn.ReadMessage()
+	})
+
+	return srv, acks
+}
+
+func TestSocketMode_Run(t *testing.T) {
+	t.Parallel()
+
+	srv, acks := fakeSocketServer(t, [][]Envelope{
+		{{Type: EnvelopeEventsAPI, EnvelopeID: "env1", Payload: json.RawMessage(`{"type":"event_callback"}`)}},
+		{{Type: EnvelopeSlashCommands, EnvelopeID: "env2", Payload: json.RawMessage(`{"text":"set 1000"}`)}},
+	})
+
+	sm := NewSocketMode("xapp-token", WithBaseURL(srv.URL))
+	sm.api.sleep = func(context.Context, time.Duration) error { return nil }
+
+	var (
+		mu  sync.Mutex
+		got []string
+	)
+
+	h := func(ctx context.Context, env *Envelope) (interface{}, error) {
+		mu.Lock()
+		got = append(got, env.Type)
+		mu.Unlock()
+
+		if env.Type == EnvelopeSlashCommands {
+			return map[string]string{"text": "ok"}, nil
+		}
+
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() { done <- sm.Run(ctx, h) }()
+
+	for _, want := range []ack{{EnvelopeID: "env1"}, {EnvelopeID: "env2", Payload: map[string]interface{}{"text": "ok"}}} {
+		select {
+		case a := <-acks:
+			wantJSON, _ := json.Marshal(want)
+			gotJSON, _ := json.Marshal(a)
+
+			if string(gotJSON) != string(wantJSON) {
+				t.Errorf("incorrect ack: want %s, got %s", wantJSON, gotJSON)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for ack of %s", want.EnvelopeID)
+		}
+	}
+
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run returned error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run didn't return after cancel")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(got) != 2 || got[0] != EnvelopeEventsAPI || got[1] != EnvelopeSlashCommands {
+		t.Errorf("incorrect envelopes handled: %v", got)
+	}
+}
+
+func TestSocketMode_Run_unacknowledgedOnError(t *testing.T) {
+	t.Parallel()
+
+	srv, acks := fakeSocketServer(t, [][]Envelope{
+		{
+			{Type: EnvelopeEventsAPI, EnvelopeID: "failed"},
+			{Type: EnvelopeEventsAPI, EnvelopeID: "succeeded"},
+		},
+	})
+
+	sm := NewSocketMode("xapp-token", WithBaseURL(srv.URL))
+
+	h := func(ctx context.Context, env *Envelope) (interface{}, error) {
+		if env.EnvelopeID == "failed" {
+			return nil, context.DeadlineExceeded
+		}
+
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() { _ = sm.Run(ctx, h) }()
+
+	select {
+	case a := <-acks:
+		if a.EnvelopeID != "succeeded" {
+			t.Errorf("failed envelope must not be acknowledged: %s", a.EnvelopeID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for ack")
+	}
+}
+
+func TestSocketMode_Run_invalidAuth(t *testing.T) {
+	t.Parallel()
+
+	var opens int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		opens++
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":false,"error":"invalid_auth"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	sm := NewSocketMode("xapp-token", WithBaseURL(srv.URL))
+	sm.api.sleep = func(context.Context, time.Duration) error { return nil }
+
+	var reported []error
+	sm.OnError(func(err error) { reported = append(reported, err) })
+
+	h := func(context.Context, *Envelope) (interface{}, error) { return nil, nil }
+
+	if err := sm.Run(context.Background(), h); !errors.Is(err, ErrInvalidAuth) {
+		t.Errorf("Run should stop with ErrInvalidAuth: %v", err)
+	}
+
+	if opens != 1 || len(reported) != 0 {
+		t.Errorf("invalid auth should not be retried: %d opens, %v", opens, reported)
+	}
+}
+
+func TestSocketMode_Run_backoff(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	t.Cleanup(srv.Close)
+
+	sm := NewSocketMode("xapp-token", WithBaseURL(srv.URL))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var waits []time.Duration
+
+	sm.api.sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		if len(waits) == 8 {
+			cancel()
+			return context.Canceled
+		}
+
+		return nil
+	}
+
+	var reported int
+	sm.OnError(func(error) { reported++ })
+
+	if err := sm.Run(ctx, func(context.Context, *Envelope) (interface{}, error) { return nil, nil }); err != nil {
+		t.Errorf("Run returned error: %v", err)
+	}
+
+	if reported != len(waits) {
+		t.Errorf("every connection error should be reported: %d, %d", reported, len(waits))
+	}
+
+	for i, d := range waits {
+		limit := socketModeMaxBackoff
+		if i < 6 {
+			limit = socketModeMinBackoff << i
+		}
+
+		if d < limit/2 || d >= limit {
+			t.Errorf("wait %d should be in [%s, %s), but %s", i, limit/2, limit, d)
+		}
+	}
+}
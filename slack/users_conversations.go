@@ -0,0 +1,53 @@
+package slack
+
+import (
+	"context"
+	"net/url"
+)
+
+// usersConversationsLimit is the page size of users.conversations.
+const usersConversationsLimit = "200"
+
+// Conversation is a channel.
+type Conversation struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type usersConversationsRes struct {
+	apiResponse
+	Channels         []*Conversation `json:"channels"`
+	ResponseMetadata struct {
+		NextCursor string `json:"next_cursor"`
+	} `json:"response_metadata"`
+}
+
+// UsersConversations returns channels which the user is a member of.
+// Private channels are limited to those the bot is also a member of.
+// https://api.slack.com/methods/users.conversations
+func (c *client) UsersConversations(ctx context.Context, userID string) ([]*Conversation, error) {
+	var convs []*Conversation
+
+	values := url.Values{
+		"user":             {userID},
+		"types":            {"public_channel,private_channel"},
+		"exclude_archived": {"true"},
+		"limit":            {usersConversationsLimit},
+	}
+
+	for {
+		var res usersConversationsRes
+
+		if err := c.postForm(ctx, "users.conversations", values, &res); err != nil {
+			return nil, err
+		}
+
+		convs = append(convs, res.Channels...)
+
+		if res.ResponseMetadata.NextCursor == "" {
+			return convs, nil
+		}
+
+		values.Set("cursor", res.ResponseMetadata.NextCursor)
+	}
+}
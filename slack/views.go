@@ -0,0 +1,41 @@
+package slack
+
+import (
+	"context"
+)
+
+// ViewsOpenReq is a request for views.open method.
+// https://api.slack.com/methods/views.open
+type ViewsOpenReq struct {
+	TriggerID string `json:"trigger_id"`
+	View      *View  `json:"view"`
+}
+
+type viewsOpenRes struct {
+	apiResponse
+}
+
+// ViewsOpen opens a modal. The trigger ID expires in 3 seconds.
+func (c *client) ViewsOpen(ctx context.Context, r *ViewsOpenReq) error {
+	var res viewsOpenRes
+
+	return c.post(ctx, "views.open", r, &res)
+}
+
+// ViewsPublishReq is a request for views.publish method.
+// https://api.slack.com/methods/views.publish
+type ViewsPublishReq struct {
+	UserID string `json:"user_id"`
+	View   *View  `json:"view"`
+}
+
+type viewsPublishRes struct {
+	apiResponse
+}
+
+// ViewsPublish publishes the home tab of the user.
+func (c *client) ViewsPublish(ctx context.Context, r *ViewsPublishReq) error {
+	var res viewsPublishRes
+
+	return c.post(ctx, "views.publish", r, &res)
+}
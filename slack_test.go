@@ -7,7 +7,11 @@ import (
 )
 
 type slackMock struct {
-	recorder []*slack.ChatPostMessageReq
+	recorder   []*slack.ChatPostMessageReq
+	ephemerals []*slack.ChatPostEphemeralReq
+	views      []*slack.ViewsOpenReq
+	homes      []*slack.ViewsPublishReq
+	convs      []*slack.Conversation
 }
 
 func newSlackMock() slack.Client {
@@ -16,11 +20,34 @@ func newSlackMock() slack.Client {
 	}
 }
 
+func (c *slackMock) AuthTest(ctx context.Context) (*slack.AuthTestRes, error) {
+	return &slack.AuthTestRes{TeamID: "T0001", UserID: "U0001"}, nil
+}
+
 func (c *slackMock) ChatPostMessage(ctx context.Context, r *slack.ChatPostMessageReq) error {
 	c.recorder = append(c.recorder, r)
 	return nil
 }
 
+func (c *slackMock) ChatPostEphemeral(ctx context.Context, r *slack.ChatPostEphemeralReq) error {
+	c.ephemerals = append(c.ephemerals, r)
+	return nil
+}
+
 func (c *slackMock) requests() []*slack.ChatPostMessageReq {
 	return c.recorder
 }
+
+func (c *slackMock) ViewsOpen(ctx context.Context, r *slack.ViewsOpenReq) error {
+	c.views = append(c.views, r)
+	return nil
+}
+
+func (c *slackMock) ViewsPublish(ctx context.Context, r *slack.ViewsPublishReq) error {
+	c.homes = append(c.homes, r)
+	return nil
+}
+
+func (c *slackMock) UsersConversations(ctx context.Context, userID string) ([]*slack.Conversation, error) {
+	return c.convs, nil
+}
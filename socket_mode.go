@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/nownabe/moneysaver/slack"
+	slackgo "github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// handleEnvelope dispatches an envelope received over Socket Mode to the same processors as HTTP endpoints.
+// Returning an error leaves the envelope unacknowledged so that Slack retries it.
+func (h *handler) handleEnvelope(ctx context.Context, env *slack.Envelope) (resp interface{}, err error) {
+	ctx, end := startSpan(ctx, "handleEnvelope")
+	defer end(&err)
+
+	ctx = withLogAttrs(ctx, slog.String("envelopeId", env.EnvelopeID), slog.String("envelopeType", env.Type))
+
+	if h.logBody {
+		logger.DebugContext(ctx, "envelope payload", slog.String("body", redactBody(env.Payload)))
+	}
+
+	switch env.Type {
+	case slack.EnvelopeEventsAPI:
+		err = h.handleEventsEnvelope(ctx, env.Payload)
+	case slack.EnvelopeSlashCommands:
+		resp, err = h.handleCommandsEnvelope(ctx, env.Payload)
+	case slack.EnvelopeInteractive:
+		resp, err = h.handleInteractiveEnvelope(ctx, env.Payload)
+	default:
+		logger.WarnContext(ctx, "unsupported envelope type")
+	}
+
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to handle envelope", slog.Any("err", err))
+
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (h *handler) handleEventsEnvelope(ctx context.Context, payload json.RawMessage) error {
+	ev, err := slackevents.ParseEvent(payload, slackevents.OptionNoVerifyToken())
+	if err != nil {
+		return fmt.Errorf("slackevents.ParseEvent: %w", err)
+	}
+
+	ctx = withLogAttrs(ctx, eventLogAttrs(ev)...)
+	ctx = h.withTeam(ctx, ev.TeamID, ev.EnterpriseID)
+
+	if err := h.eventProcessor.process(ctx, ev); err != nil {
+		return fmt.Errorf("h.eventProcessor.process: %w", err)
+	}
+
+	return nil
+}
+
+func (h *handler) handleCommandsEnvelope(ctx context.Context, payload json.RawMessage) (*slackgo.Msg, error) {
+	var s slackgo.SlashCommand
+	if err := json.Unmarshal(payload, &s); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	ctx = withLogAttrs(ctx,
+		slog.String("teamId", s.TeamID), slog.String("channelId", s.ChannelID), slog.String("command", s.Command))
+	ctx = h.withTeam(ctx, s.TeamID, s.EnterpriseID)
+
+	resp, err := h.commandProcessor.process(ctx, s)
+	if err != nil {
+		return nil, fmt.Errorf("h.commandProcessor.process: %w", err)
+	}
+
+	return resp, nil
+}
+
+func (h *handler) handleInteractiveEnvelope(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	var cb slackgo.InteractionCallback
+	if err := json.Unmarshal(payload, &cb); err != nil {
+		return nil, fmt.Errorf("json.Unmarshal: %w", err)
+	}
+
+	ctx = withLogAttrs(ctx, interactionLogAttrs(&cb)...)
+	ctx = h.withTeam(ctx, cb.Team.ID, cb.Enterprise.ID)
+
+	resp, err := h.interactionProcessor.process(ctx, &cb)
+	if err != nil {
+		return nil, fmt.Errorf("h.interactionProcessor.process: %w", err)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/nownabe/moneysaver/slack"
+)
+
+func Test_handler_handleEnvelope(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		env     *slack.Envelope
+		wantErr bool
+	}{
+		"interactive": {
+			env: &slack.Envelope{Type: slack.EnvelopeInteractive, Payload: json.RawMessage(`{"type":"block_actions","actions":[{"action_id":"unknown"}]}`)},
+		},
+		"unsupported type": {
+			env: &slack.Envelope{Type: "unknown"},
+		},
+		"broken event": {
+			env:     &slack.Envelope{Type: slack.EnvelopeEventsAPI, Payload: json.RawMessage(`{`)},
+			wantErr: true,
+		},
+		"broken command": {
+			env:     &slack.Envelope{Type: slack.EnvelopeSlashCommands, Payload: json.RawMessage(`{`)},
+			wantErr: true,
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			h := &handler{interactionProcessor: &interactionProcessor{}}
+
+			resp, err := h.handleEnvelope(context.Background(), c.env)
+			if c.wantErr != (err != nil) {
+				t.Errorf("incorrect error: %v", err)
+			}
+
+			if resp != nil {
+				t.Errorf("unexpected ack payload: %v", resp)
+			}
+		})
+	}
+}
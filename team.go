@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/nownabe/moneysaver/slack"
+)
+
+// How long resolved clients are reused before the installation is read again
+const teamClientTTL = 5 * time.Minute
+
+var errNoTeam = errors.New("no team in context")
+
+type teamContextKey struct{}
+
+// team is the workspace which the request came from.
+type team struct {
+	id string
+	// Set when the workspace is in an Enterprise Grid organization
+	enterpriseID string
+}
+
+// withTeam scopes storage and Slack clients to the workspace.
+// Without it, documents are at the root for a single workspace.
+func withTeam(ctx context.Context, id, enterpriseID string) context.Context {
+	return context.WithValue(ctx, teamContextKey{}, team{id: id, enterpriseID: enterpriseID})
+}
+
+func teamFrom(ctx context.Context) team {
+	t, _ := ctx.Value(teamContextKey{}).(team)
+	return t
+}
+
+// teamCollection returns the collection of the workspace in ctx.
+func teamCollection(ctx context.Context, c *firestore.Client, name string) *firestore.CollectionRef {
+	if t := teamFrom(ctx); t.id != "" {
+		return c.Collection(teamCollectionName).Doc(t.id).Collection(name)
+	}
+
+	return c.Collection(name)
+}
+
+type cachedClient struct {
+	client    slack.Client
+	expiresAt time.Time
+}
+
+// teamClients is a slack.Client which calls Web API with the bot token of the workspace in ctx.
+// Installations to the organization are used for workspaces in it.
+type teamClients struct {
+	repo *installationRepo
+	opts []slack.Option
+	now  func() time.Time
+
+	mu      sync.Mutex
+	clients map[string]cachedClient
+}
+
+func newTeamClients(repo *installationRepo, opts ...slack.Option) *teamClients {
+	return &teamClients{
+		repo:    repo,
+		opts:    opts,
+		now:     time.Now,
+		clients: map[string]cachedClient{},
+	}
+}
+
+func (c *teamClients) client(ctx context.Context) (slack.Client, error) {
+	t := teamFrom(ctx)
+	if t.id == "" {
+		return nil, errNoTeam
+	}
+
+	c.mu.Lock()
+	cached, ok := c.clients[t.id]
+	c.mu.Unlock()
+
+	if ok && c.now().Before(cached.expiresAt) {
+		return cached.client, nil
+	}
+
+	ins, err := c.installation(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
+	sc := slack.New(ins.BotToken, c.opts...)
+
+	c.mu.Lock()
+	c.clients[t.id] = cachedClient{client: sc, expiresAt: c.now().Add(teamClientTTL)}
+	c.mu.Unlock()
+
+	return sc, nil
+}
+
+// installation returns the installation to the workspace, or to its organization.
+func (c *teamClients) installation(ctx context.Context, t team) (*installation, error) {
+	ins, err := c.repo.findByID(ctx, t.id)
+	if errors.Is(err, errNotFound) && t.enterpriseID != "" {
+		ins, err = c.repo.findByID(ctx, t.enterpriseID)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("c.repo.findByID(%s): %w", t.id, err)
+	}
+
+	return ins, nil
+}
+
+// forget drops cached clients of the installation, e.g. when it's reinstalled or uninstalled.
+func (c *teamClients) forget(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Workspaces in an organization are cached by their team IDs, so drop all of them.
+	if id != "" && id[0] == 'E' {
+		c.clients = map[string]cachedClient{}
+		return
+	}
+
+	delete(c.clients, id)
+}
+
+// uninstall deletes the installation to the workspace in ctx, or to its organization.
+// Expenditures are kept so that they're back on reinstallation.
+func (c *teamClients) uninstall(ctx context.Context) error {
+	t := teamFrom(ctx)
+	if t.id == "" {
+		return errNoTeam
+	}
+
+	ins, err := c.installation(ctx, t)
+	if errors.Is(err, errNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if err := c.repo.delete(ctx, ins.ID); err != nil {
+		return fmt.Errorf("c.repo.delete: %w", err)
+	}
+
+	c.forget(ins.ID)
+
+	return nil
+}
+
+func (c *teamClients) AuthTest(ctx context.Context) (*slack.AuthTestRes, error) {
+	sc, err := c.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return sc.AuthTest(ctx)
+}
+
+func (c *teamClients) ChatPostMessage(ctx context.Context, r *slack.ChatPostMessageReq) error {
+	sc, err := c.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	return sc.ChatPostMessage(ctx, r)
+}
+
+func (c *teamClients) ChatPostEphemeral(ctx context.Context, r *slack.ChatPostEphemeralReq) error {
+	sc, err := c.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	return sc.ChatPostEphemeral(ctx, r)
+}
+
+func (c *teamClients) ViewsOpen(ctx context.Context, r *slack.ViewsOpenReq) error {
+	sc, err := c.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	return sc.ViewsOpen(ctx, r)
+}
+
+func (c *teamClients) ViewsPublish(ctx context.Context, r *slack.ViewsPublishReq) error {
+	sc, err := c.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	return sc.ViewsPublish(ctx, r)
+}
+
+func (c *teamClients) UsersConversations(ctx context.Context, userID string) ([]*slack.Conversation, error) {
+	sc, err := c.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return sc.UsersConversations(ctx, userID)
+}
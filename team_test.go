@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_teamCollection(t *testing.T) {
+	t.Parallel()
+
+	fs := getFirestoreClient(t)
+	ctx := context.Background()
+
+	cases := map[string]struct {
+		ctx  context.Context
+		path string
+	}{
+		"single workspace": {ctx: ctx, path: "channels"},
+		"team":             {ctx: withTeam(ctx, "T0001", ""), path: "teams/T0001/channels"},
+		"organization":     {ctx: withTeam(ctx, "T0001", "E0001"), path: "teams/T0001/channels"},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if p := teamCollection(c.ctx, fs, collectionName).Path; p != fs.Collection(c.path).Path {
+				t.Errorf("incorrect path: %s", p)
+			}
+		})
+	}
+}
+
+func Test_teamClients_client(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fs := getFirestoreClient(t)
+	repo := &installationRepo{fs, nil}
+
+	defer flushStore(t)
+
+	for _, ins := range []*installation{
+		{ID: "T0001", BotToken: "xoxb-team"},
+		{ID: "E0001", BotToken: "xoxb-org"},
+	} {
+		if err := repo.save(ctx, ins); err != nil {
+			t.Fatalf("repo.save: %v", err)
+		}
+	}
+
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	c := newTeamClients(repo)
+	c.now = func() time.Time { return now }
+
+	if _, err := c.client(ctx); !errors.Is(err, errNoTeam) {
+		t.Errorf("client without team should fail: %v", err)
+	}
+
+	if _, err := c.client(withTeam(ctx, "T0002", "")); !errors.Is(err, errNotFound) {
+		t.Errorf("client of unknown team should fail: %v", err)
+	}
+
+	if _, err := c.client(withTeam(ctx, "T0003", "E0001")); err != nil {
+		t.Errorf("client of team in organization should fall back to its installation: %v", err)
+	}
+
+	sc, err := c.client(withTeam(ctx, "T0001", ""))
+	if err != nil {
+		t.Fatalf("c.client: %v", err)
+	}
+
+	if err := c.uninstall(withTeam(ctx, "T0001", "")); err != nil {
+		t.Fatalf("c.uninstall: %v", err)
+	}
+
+	if _, err := c.client(withTeam(ctx, "T0001", "")); !errors.Is(err, errNotFound) {
+		t.Errorf("client of uninstalled team should fail: %v", err)
+	}
+
+	if err := repo.save(ctx, &installation{ID: "T0001", BotToken: "xoxb-team"}); err != nil {
+		t.Fatalf("repo.save: %v", err)
+	}
+
+	reinstalled, err := c.client(withTeam(ctx, "T0001", ""))
+	if err != nil || reinstalled == sc {
+		t.Fatalf("reinstalled team should have a new client: %v", err)
+	}
+
+	if cached, _ := c.client(withTeam(ctx, "T0001", "")); cached != reinstalled {
+		t.Errorf("client should be cached")
+	}
+
+	if err := c.uninstall(withTeam(ctx, "T0003", "E0001")); err != nil {
+		t.Fatalf("c.uninstall: %v", err)
+	}
+
+	if _, err := repo.findByID(ctx, "E0001"); !errors.Is(err, errNotFound) {
+		t.Errorf("installation to organization should be deleted: %v", err)
+	}
+
+	now = now.Add(teamClientTTL)
+
+	if expired, _ := c.client(withTeam(ctx, "T0001", "")); expired == reinstalled {
+		t.Errorf("client should be renewed after TTL")
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracerName = "github.com/nownabe/moneysaver"
+
+	tracingShutdownTimeout = 5 * time.Second
+)
+
+func init() {
+	// W3C trace context is propagated even if tracing is disabled.
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+func tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// setupTracing installs the global tracer provider with the exporter,
+// which is none, stdout or otlp. OTLP exporter is configured by OTEL_EXPORTER_OTLP_* variables.
+func setupTracing(ctx context.Context, exporter string, ratio float64, w io.Writer) (io.Closer, error) {
+	var (
+		exp sdktrace.SpanExporter
+		err error
+	)
+
+	switch exporter {
+	case "", "none":
+		return nopCloser{}, nil
+	case "stdout":
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	case "otlp":
+		exp, err = otlptracehttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter: %s", exporter)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s exporter: %w", exporter, err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName("moneysaver"),
+			semconv.ServiceVersion(version),
+		)),
+	)
+
+	otel.SetTracerProvider(tp)
+
+	return &tracerProviderCloser{tp}, nil
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// tracerProviderCloser flushes spans on shutdown.
+type tracerProviderCloser struct {
+	tp *sdktrace.TracerProvider
+}
+
+func (c *tracerProviderCloser) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+	defer cancel()
+
+	if err := c.tp.Shutdown(ctx); err != nil {
+		return fmt.Errorf("c.tp.Shutdown: %w", err)
+	}
+
+	return nil
+}
+
+// tracingMiddleware starts a server span continuing the W3C trace context of the request.
+func tracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		ctx, span := tracer().Start(ctx, r.Method, trace.WithSpanKind(trace.SpanKindServer))
+		defer span.End()
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			span.SetName(r.Method + " " + rctx.RoutePattern())
+			span.SetAttributes(semconv.HTTPRoute(rctx.RoutePattern()))
+		}
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		span.SetAttributes(semconv.HTTPRequestMethodKey.String(r.Method), semconv.HTTPResponseStatusCode(status))
+
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
+
+// startSpan starts an internal span. Call the returned function with the result error to end it.
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, func(*error)) {
+	ctx, span := tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+
+	return ctx, func(err *error) {
+		if err != nil && *err != nil {
+			span.RecordError(*err)
+			span.SetStatus(codes.Error, errorClass(*err))
+		}
+
+		span.End()
+	}
+}
+
+// instrumentStorage records a span and metrics of a Firestore operation.
+func instrumentStorage(ctx context.Context, operation string) (context.Context, func(*error)) {
+	start := time.Now()
+
+	ctx, end := startSpan(ctx, "firestore."+operation, semconv.DBSystemKey.String("firestore"))
+
+	return ctx, func(err *error) {
+		observeStorage(operation, start, err)
+		end(err)
+	}
+}
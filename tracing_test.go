@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// Not parallel since it replaces the global tracer provider.
+func Test_tracing(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+
+	defer otel.SetTracerProvider(prev)
+
+	const (
+		traceID  = "4bf92f3577b34da6a3ce929d0e0e4736"
+		parentID = "00f067aa0ba902b7"
+	)
+
+	r := newRouter(&handler{}, &healthHandler{}, newVerifier("secret"))
+
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-"+parentID+"-01")
+
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	_, end := startSpan(context.Background(), "failing")
+	err := errors.New("boom")
+	end(&err)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range sr.Ended() {
+		spans[s.Name()] = s
+	}
+
+	s, ok := spans["GET /healthz"]
+	if !ok {
+		t.Fatalf("server span should be recorded: %v", spans)
+	}
+
+	if s.SpanContext().TraceID().String() != traceID || s.Parent().SpanID().String() != parentID {
+		t.Errorf("server span should continue the trace context: %s, %s", s.SpanContext().TraceID(), s.Parent().SpanID())
+	}
+
+	s, ok = spans["failing"]
+	if !ok {
+		t.Fatalf("failing span should be recorded: %v", spans)
+	}
+
+	if s.Status().Code != codes.Error || len(s.Events()) != 1 {
+		t.Errorf("error should be recorded: %v, %v", s.Status(), s.Events())
+	}
+}
+
+func Test_setupTracing_unknownExporter(t *testing.T) {
+	t.Parallel()
+
+	if _, err := setupTracing(context.Background(), "zipkin", 1, nil); err == nil {
+		t.Error("setupTracing should reject unknown exporters")
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/slack-go/slack"
+)
+
+const (
+	// Deleted expenditures are indexed under each channel until they're restored or purged.
+	trashCollectionName = "trash"
+
+	maxTrashEntries = 20
+)
+
+// trashEntry indexes a deleted expenditure, since expenditures are in collections by month.
+type trashEntry struct {
+	Ref       *firestore.DocumentRef `firestore:"ref"`
+	DeletedAt time.Time              `firestore:"deletedAt"`
+	DeletedBy string                 `firestore:"deletedBy,omitempty"`
+}
+
+func trashCollection(ctx context.Context, c *firestore.Client, chID string) *firestore.CollectionRef {
+	return teamCollection(ctx, c, collectionName).Doc(chID).Collection(trashCollectionName)
+}
+
+// trashID returns the document ID of the entry, which can't contain slashes of the key.
+func trashID(ex *expenditure) string {
+	return strings.ReplaceAll(ex.key(), "/", "_")
+}
+
+// listDeleted returns expenditures in the trash of the channel, recently deleted first.
+func (r *expenditureRepo) listDeleted(ctx context.Context, chID string, limit int) (_ []*expenditure, err error) {
+	ctx, done := instrumentStorage(ctx, "expenditures.listDeleted")
+	defer done(&err)
+
+	docs, err := trashCollection(ctx, r.Client, chID).OrderBy("deletedAt", firestore.Desc).Limit(limit).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("trashCollection.Documents.GetAll: %w", err)
+	}
+
+	refs := make([]*firestore.DocumentRef, 0, len(docs))
+
+	for _, doc := range docs {
+		var e trashEntry
+		if err := doc.DataTo(&e); err != nil {
+			return nil, fmt.Errorf("doc.DataTo: %w", err)
+		}
+
+		refs = append(refs, e.Ref)
+	}
+
+	if len(refs) == 0 {
+		return nil, nil
+	}
+
+	exDocs, err := r.GetAll(ctx, refs)
+	if err != nil {
+		return nil, fmt.Errorf("r.GetAll: %w", err)
+	}
+
+	exs := make([]*expenditure, 0, len(exDocs))
+
+	for _, doc := range exDocs {
+		if !doc.Exists() {
+			continue
+		}
+
+		var ex expenditure
+		if err := doc.DataTo(&ex); err != nil {
+			return nil, fmt.Errorf("doc.DataTo: %w", err)
+		}
+
+		if !ex.deleted() {
+			continue
+		}
+
+		ex.Channel = chID
+		ex.TS = doc.Ref.ID
+		exs = append(exs, &ex)
+	}
+
+	return exs, nil
+}
+
+// purgeDeleted permanently deletes expenditures of all channels deleted before the time,
+// and returns how many are purged.
+func (r *expenditureRepo) purgeDeleted(ctx context.Context, before time.Time) (n int, err error) {
+	ctx, done := instrumentStorage(ctx, "expenditures.purgeDeleted")
+	defer done(&err)
+
+	docs, err := r.CollectionGroup(trashCollectionName).Where("deletedAt", "<", before).Documents(ctx).GetAll()
+	if err != nil {
+		return 0, fmt.Errorf("r.CollectionGroup.Documents.GetAll: %w", err)
+	}
+
+	var errs []error
+
+	for _, doc := range docs {
+		var e trashEntry
+		if err := doc.DataTo(&e); err != nil {
+			errs = append(errs, fmt.Errorf("doc.DataTo(%s): %w", doc.Ref.Path, err))
+			continue
+		}
+
+		purged, err := r.purgeEntry(ctx, doc.Ref, e.Ref)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("r.purgeEntry(%s): %w", doc.Ref.Path, err))
+			continue
+		}
+
+		if purged {
+			n++
+		}
+	}
+
+	return n, errors.Join(errs...)
+}
+
+// purgeEntry deletes the expenditure and its entry. Entries of restored ones are just deleted.
+func (r *expenditureRepo) purgeEntry(ctx context.Context, trashRef, exRef *firestore.DocumentRef) (bool, error) {
+	var purged bool
+
+	err := r.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		purged = false
+
+		data, err := txData(tx, exRef)
+		if err != nil {
+			return err
+		}
+
+		if field(data, "deletedAt") != nil {
+			if err := tx.Delete(exRef); err != nil {
+				return fmt.Errorf("tx.Delete: %w", err)
+			}
+
+			purged = true
+		}
+
+		if err := tx.Delete(trashRef); err != nil {
+			return fmt.Errorf("tx.Delete: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("r.RunTransaction: %w", err)
+	}
+
+	return purged, nil
+}
+
+// trashPurger permanently deletes expenditures in the trash longer than the retention.
+type trashPurger struct {
+	repo      *expenditureRepo
+	retention time.Duration
+}
+
+// run purges expenditures periodically until ctx is done.
+func (p *trashPurger) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := p.repo.purgeDeleted(ctx, time.Now().Add(-p.retention))
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to purge deleted expenditures", slog.Int("purged", n), slog.Any("err", err))
+		} else if n > 0 {
+			logger.InfoContext(ctx, "purged deleted expenditures", slog.Int("purged", n))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// processTrash lists deleted expenditures of the channel, or restores one by `restore <key>`.
+func (p *commandProcessor) processTrash(
+	ctx context.Context, c slack.SlashCommand, l lang, ch *channel, args []string,
+) (*slack.Msg, error) {
+	if ch == nil {
+		return &slack.Msg{Text: l.t(msgSetBudgetFirst)}, nil
+	}
+
+	// Don't modify ch with the config file since it may be saved.
+	cs := p.settings.get().channel(ch.ID)
+	view := *ch
+	cs.applyTo(&view)
+
+	switch {
+	case len(args) == 0:
+		exs, err := p.expenditureRepo.listDeleted(ctx, ch.ID, maxTrashEntries)
+		if err != nil {
+			return nil, wrap(http.StatusInternalServerError, "p.expenditureRepo.listDeleted: %w", err)
+		}
+
+		if len(exs) == 0 {
+			return &slack.Msg{Text: l.t(msgTrashEmpty)}, nil
+		}
+
+		lines := []string{l.t(msgTrashTitle)}
+		for _, ex := range exs {
+			ex.in(cs.location())
+			lines = append(lines, "• "+formatTrashItem(l, &view, ex, cs.location()))
+		}
+
+		return &slack.Msg{Text: strings.Join(lines, "\n")}, nil
+	case len(args) == 2 && args[0] == "restore":
+		ex, err := p.expenditureRepo.findDeleted(ctx, ch.ID, args[1])
+		if errors.Is(err, errNotFound) {
+			return &slack.Msg{Text: l.t(msgTrashNotFound, args[1])}, nil
+		} else if err != nil {
+			return nil, wrap(http.StatusInternalServerError, "p.expenditureRepo.findDeleted: %w", err)
+		}
+
+		ex.in(cs.location())
+
+		if !ch.canDelete(c.UserID, ex) {
+			return &slack.Msg{Text: l.t(msgCannotRestoreOthers, ch.CanDeleteOthers.label(l))}, nil
+		}
+
+		if err := p.expenditureRepo.restore(ctx, ex); err != nil {
+			if errors.Is(err, errNotFound) {
+				return &slack.Msg{Text: l.t(msgTrashNotFound, args[1])}, nil
+			}
+
+			return nil, wrap(http.StatusInternalServerError, "p.expenditureRepo.restore: %w", err)
+		}
+
+		expendituresTotal.WithLabelValues(string(opRestored)).Inc()
+
+		p.interactions.replyLater(ctx, &view, cs, c.UserID, ex, opRestored)
+
+		return &slack.Msg{Text: l.t(msgTrashRestored, ex.key())}, nil
+	}
+
+	return &slack.Msg{Text: l.t(msgTrashUsage)}, nil
+}
+
+func formatTrashItem(l lang, ch *channel, ex *expenditure, loc *time.Location) string {
+	by := actorLabel(l, ex.DeletedBy)
+
+	text := l.t(msgTrashItem,
+		ex.key(), ex.effective().Format(dateLayout), humanizeIn(ex.Amount, ch.currency()),
+		by, ex.DeletedAt.In(loc).Format(dateLayout))
+
+	if ex.Memo != "" {
+		text += " " + ex.Memo
+	}
+
+	return text
+}
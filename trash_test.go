@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_formatTrashItem(t *testing.T) {
+	t.Parallel()
+
+	ex := &expenditure{
+		Channel:   "ch1",
+		TS:        "1790000000.000100",
+		Amount:    1200,
+		Timestamp: time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC),
+		DeletedAt: time.Date(2026, 10, 2, 9, 0, 0, 0, time.UTC),
+	}
+
+	cases := map[string]struct {
+		deletedBy string
+		memo      string
+		want      string
+	}{
+		"user": {
+			deletedBy: "U1",
+			want:      "`2026-10/1790000000.000100` 2026-10-01 ¥1,200 (deleted by <@U1> on 2026-10-02)",
+		},
+		"system with memo": {
+			memo: "lunch",
+			want: "`2026-10/1790000000.000100` 2026-10-01 ¥1,200 (deleted by MoneySaver on 2026-10-02) lunch",
+		},
+	}
+
+	for name, c := range cases {
+		c := c
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ex := *ex
+			ex.DeletedBy = c.deletedBy
+			ex.Memo = c.memo
+
+			if got := formatTrashItem(langEN, &channel{ID: "ch1"}, &ex, time.UTC); got != c.want {
+				t.Errorf("formatTrashItem() = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func Test_expenditureRepo_trash(t *testing.T) {
+	t.Parallel()
+
+	fs := getFirestoreClient(t)
+	ctx := withActor(context.Background(), "U1", "Ev0001")
+	repo := &expenditureRepo{fs}
+
+	defer flushStore(t)
+
+	ts := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	kept := &expenditure{Channel: "ch1", TS: "1790000000.000100", Amount: 1000, Timestamp: ts}
+	deleted := &expenditure{Channel: "ch1", TS: "1790000000.000200", Amount: 300, Timestamp: ts}
+	// Sorts after the deleted one, so it's read after it.
+	later := &expenditure{Channel: "ch1", TS: "1790000000.000300", Amount: 50, Timestamp: ts}
+
+	for _, ex := range []*expenditure{kept, deleted, later} {
+		if err := repo.add(ctx, ex); err != nil {
+			t.Fatalf("repo.add: %v", err)
+		}
+	}
+
+	if err := repo.delete(ctx, deleted); err != nil {
+		t.Fatalf("repo.delete: %v", err)
+	}
+
+	if err := repo.delete(ctx, deleted); !errors.Is(err, errNotFound) {
+		t.Errorf("deleting a deleted expenditure should fail: %v", err)
+	}
+
+	if total, err := repo.total(ctx, kept); err != nil || total != 1050 {
+		t.Errorf("deleted expenditures should be excluded from totals: %d, %v", total, err)
+	}
+
+	if exs, err := repo.list(ctx, "ch1", "2026-10"); err != nil || len(exs) != 2 {
+		t.Errorf("deleted expenditures should be excluded from lists: %v, %v", exs, err)
+	}
+
+	if _, err := repo.findByKey(ctx, "ch1", deleted.key()); !errors.Is(err, errNotFound) {
+		t.Errorf("deleted expenditures should not be found: %v", err)
+	}
+
+	exs, err := repo.listDeleted(ctx, "ch1", maxTrashEntries)
+	if err != nil {
+		t.Fatalf("repo.listDeleted: %v", err)
+	}
+
+	if len(exs) != 1 || exs[0].key() != deleted.key() || exs[0].DeletedBy != "U1" {
+		t.Errorf("incorrect trash: %v", exs)
+	}
+
+	if err := repo.restore(ctx, deleted); err != nil {
+		t.Fatalf("repo.restore: %v", err)
+	}
+
+	if err := repo.restore(ctx, deleted); !errors.Is(err, errNotFound) {
+		t.Errorf("restoring an expenditure not in the trash should fail: %v", err)
+	}
+
+	if total, err := repo.total(ctx, kept); err != nil || total != 1350 {
+		t.Errorf("restored expenditures should be included in totals: %d, %v", total, err)
+	}
+
+	if exs, err := repo.listDeleted(ctx, "ch1", maxTrashEntries); err != nil || len(exs) != 0 {
+		t.Errorf("trash should be empty: %v, %v", exs, err)
+	}
+
+	if err := repo.delete(ctx, deleted); err != nil {
+		t.Fatalf("repo.delete: %v", err)
+	}
+
+	if n, err := repo.purgeDeleted(ctx, time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Errorf("recently deleted expenditures should be kept: %d, %v", n, err)
+	}
+
+	if n, err := repo.purgeDeleted(ctx, time.Now().Add(time.Hour)); err != nil || n != 1 {
+		t.Errorf("deleted expenditures should be purged: %d, %v", n, err)
+	}
+
+	if _, err := repo.findDeleted(ctx, "ch1", deleted.key()); !errors.Is(err, errNotFound) {
+		t.Errorf("purged expenditures should not be found: %v", err)
+	}
+
+	if total, err := repo.total(ctx, kept); err != nil || total != 1050 {
+		t.Errorf("incorrect total after purge: %d, %v", total, err)
+	}
+}